@@ -10,6 +10,7 @@ import (
 	"pricetrackerbot/config"
 	"pricetrackerbot/handlers"
 	"pricetrackerbot/services"
+	"pricetrackerbot/storage"
 )
 
 const webhookEndpoint = "/webhook"
@@ -19,6 +20,7 @@ type BotFixer struct {
 	Config            *config.Configuration
 	BondsClientActive bool
 	CommandHandler    *handlers.CommandHandler
+	Storage           storage.Storage
 	TelegramBotAPI    string
 }
 
@@ -35,7 +37,13 @@ func NewBotFixer() *BotFixer {
 		return nil
 	}
 
-	botFixer.CommandHandler = handlers.NewCommandHandler(botFixer.Bot)
+	botFixer.Storage, err = storage.NewBoltStorage(botFixer.Config.StorageFile)
+	if err != nil {
+		log.Panic(err)
+		return nil
+	}
+
+	botFixer.CommandHandler = handlers.NewCommandHandler(botFixer.Bot, botFixer.Storage)
 
 	return botFixer
 }
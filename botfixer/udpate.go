@@ -8,6 +8,8 @@ import (
 	"net/http"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"pricetrackerbot/handlers"
+	"pricetrackerbot/helpers"
 )
 
 func (b *BotFixer) webhookHandler(w http.ResponseWriter, r *http.Request) {
@@ -71,10 +73,20 @@ func (b *BotFixer) handleMessage(message *tgbotapi.Message) {
 
 	log.Printf("[Bot fixer] %s wrote %s", user.FirstName, text)
 
+	if !b.CommandHandler.IsAllowed(user.ID, message.Chat.ID) {
+		log.Printf("[Bot fixer] User %d is not allowed to use the bot in chat %d", user.ID, message.Chat.ID)
+		// Only commands are answered so that the bot does not reply to every message of a group chat
+		if message.IsCommand() {
+			helpers.SendMessageHTML(b.Bot, message.Chat.ID, handlers.AccessErrorMessage(handlers.ErrAccessDenied), nil)
+		}
+
+		return
+	}
+
 	// TODO switch to the tgbotapi methods for working with messages/commands - message.IsCommand(), message.CommandArguments(), etc.
 	if message.IsCommand() {
 		b.CommandHandler.GetUserNavigationState(message.Chat.ID).BackButtonEnabled = false
-		if err := b.CommandHandler.HandleCommand(message.Chat.ID, text, nil, false); err != nil {
+		if err := b.CommandHandler.HandleCommand(message.Chat.ID, user.ID, text, nil, false); err != nil {
 			log.Printf("[Bot fixer] An error occurred while handling command: %s", err.Error())
 
 			return
@@ -84,9 +96,9 @@ func (b *BotFixer) handleMessage(message *tgbotapi.Message) {
 	}
 
 	// Handle user input after a certain command/action has requested it
-	if b.CommandHandler.AwaitingUserInput {
+	if b.CommandHandler.GetUserNavigationState(message.Chat.ID).IsAwaitingInput() {
 		b.CommandHandler.GetUserNavigationState(message.Chat.ID).BackButtonEnabled = true
-		if err := b.CommandHandler.HandleUserInput(message.Chat.ID, text, nil); err != nil {
+		if err := b.CommandHandler.HandleUserInput(message.Chat.ID, user.ID, text, nil); err != nil {
 			log.Printf("[Bot fixer] An error occurred while handling user input: %s", err.Error())
 
 			return
@@ -98,10 +110,21 @@ func (b *BotFixer) handleMessage(message *tgbotapi.Message) {
 
 func (b *BotFixer) handleButton(query *tgbotapi.CallbackQuery) {
 	command := query.Data
+
+	// Rejected buttons are answered with an alert instead of a chat message
+	if err := b.CommandHandler.CheckAccess(query.From.ID, query.Message.Chat.ID, command); err != nil {
+		log.Printf("[Bot fixer] User %d is not allowed to use button '%s' in chat %d: %s", query.From.ID, command, query.Message.Chat.ID, err.Error())
+		if _, err := b.Bot.Request(tgbotapi.NewCallbackWithAlert(query.ID, handlers.AccessErrorMessage(err))); err != nil {
+			log.Printf("[Bot fixer] Error answering callback query: %s", err.Error())
+		}
+
+		return
+	}
+
 	b.CommandHandler.GetUserNavigationState(query.Message.Chat.ID).BackButtonEnabled = true
 
 	if command == "back" {
-		if err := b.CommandHandler.HandleReturn(query.Message.Chat.ID, &query.Message.MessageID); err != nil {
+		if err := b.CommandHandler.HandleReturn(query.Message.Chat.ID, query.From.ID, &query.Message.MessageID); err != nil {
 			log.Printf("[Bot fixer] An error occurred while handling button: %s", err.Error())
 
 			return
@@ -110,7 +133,7 @@ func (b *BotFixer) handleButton(query *tgbotapi.CallbackQuery) {
 		return
 	}
 
-	if err := b.CommandHandler.HandleCommand(query.Message.Chat.ID, command, &query.Message.MessageID, false); err != nil {
+	if err := b.CommandHandler.HandleCommand(query.Message.Chat.ID, query.From.ID, command, &query.Message.MessageID, false); err != nil {
 		log.Printf("[Bot fixer] An error occurred while handling button: %s", err.Error())
 
 		return
@@ -1,10 +1,12 @@
 package clients
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
 
 	"github.com/tidwall/gjson"
 	config "pricetrackerbot/config"
@@ -12,70 +14,99 @@ import (
 )
 
 // Client for fetching data from public APIs and extracting the necessary data as defined in the tracker configuration.
+// It keeps no state between the calls, so that the trackers can share it.
 type PublicAPIClient struct {
-	trackerData *config.Tracker
+	cache *FetchCache
 }
 
-func NewPublicAPIClient() *PublicAPIClient {
-	return &PublicAPIClient{}
+func NewPublicAPIClient(cache *FetchCache) *PublicAPIClient {
+	return &PublicAPIClient{cache: cache}
 }
 
-func (c *PublicAPIClient) FetchAndExtractData(trackerData *config.Tracker) (*DataResult, error) {
-	c.trackerData = trackerData
-	dataJSON, err := c.getDataFromPublicAPI()
+func (c *PublicAPIClient) FetchAndExtractData(ctx context.Context, trackerData *config.Tracker, state *NotificationState) (*DataResult, error) {
+	trackedValue, err := c.cache.Get(ctx, config.TrackerTypeAPI, trackerData, func(lastValue *TrackedValue) (TrackedValue, error) {
+		return c.fetchValue(ctx, trackerData, lastValue)
+	})
 	if err != nil {
-		log.Println("[Public API Client] Error getting data from public API for tracker: "+c.trackerData.Code, err.Error())
 		return nil, err
 	}
 
-	extractedValue, err := c.extractDataFromPublicAPIResponse(dataJSON)
+	notification, err := ProcessNotificationCriteria(trackerData, trackedValue, state)
 	if err != nil {
-		log.Println("[Public API Client] Error extracting data from public API response for tracker: "+c.trackerData.Code, err.Error())
 		return nil, err
 	}
 
-	extractedValueFloat, extractedErr := strconv.ParseFloat(extractedValue, 64)
-	if extractedErr != nil {
-		log.Println("[Public API Client] Error converting values for tracker: "+c.trackerData.Code, extractedErr.Error())
-		return nil, extractedErr
+	return &DataResult{
+		CurrentValue:        trackedValue,
+		NotificationMessage: notification,
+	}, nil
+}
+
+func (c *PublicAPIClient) fetchValue(ctx context.Context, trackerData *config.Tracker, lastValue *TrackedValue) (TrackedValue, error) {
+	response, err := c.getDataFromPublicAPI(ctx, trackerData, lastValue)
+	if errors.Is(err, services.ErrNotModified) && lastValue != nil {
+		log.Println("[Public API Client] Data not modified since the last fetch for tracker: " + trackerData.Code)
+		return *lastValue, nil
 	}
 
-	notification, err := ProcessNotificationCriteria(c.trackerData, extractedValueFloat)
 	if err != nil {
-		return nil, err
+		log.Println("[Public API Client] Error getting data from public API for tracker: "+trackerData.Code, err.Error())
+		return TrackedValue{}, err
 	}
 
-	result := &DataResult{
-		CurrentValue:        extractedValueFloat,
-		NotificationMessage: notification,
+	extractedValue, err := c.extractDataFromPublicAPIResponse(trackerData, response.Body)
+	if err != nil {
+		log.Println("[Public API Client] Error extracting data from public API response for tracker: "+trackerData.Code, err.Error())
+		return TrackedValue{}, err
 	}
 
-	c.trackerData = nil
+	trackedValue := TrackedValue{Text: extractedValue, IsText: trackerData.GetValueType() == config.ValueTypeText, validators: response.Validators}
+	if !trackedValue.IsText {
+		extractedValueFloat, extractedErr := strconv.ParseFloat(extractedValue, 64)
+		if extractedErr != nil {
+			log.Println("[Public API Client] Error converting values for tracker: "+trackerData.Code, extractedErr.Error())
+			return TrackedValue{}, &ParseError{Value: extractedValue, Err: extractedErr}
+		}
+
+		trackedValue.Number = extractedValueFloat
+	}
 
-	return result, nil
+	return trackedValue, nil
 }
 
-func (c *PublicAPIClient) getDataFromPublicAPI() ([]byte, error) {
-	response, err := services.GetRequest(c.trackerData.DataURL)
+func (c *PublicAPIClient) getDataFromPublicAPI(ctx context.Context, trackerData *config.Tracker, lastValue *TrackedValue) (*services.Response, error) {
+	request, err := buildRequest(trackerData)
 	if err != nil {
-		log.Println("[Public API Client] Error getting data from public API for tracker: "+c.trackerData.Code, err.Error())
-
 		return nil, err
 	}
 
-	return response, nil
+	if lastValue != nil {
+		request.Validators = lastValue.validators
+	}
+
+	response, err := services.Do(ctx, request)
+	if err != nil && !errors.Is(err, services.ErrNotModified) {
+		log.Println("[Public API Client] Error getting data from public API for tracker: "+trackerData.Code, err.Error())
+	}
+
+	return response, err
 }
 
-func (c *PublicAPIClient) extractDataFromPublicAPIResponse(responseJSON []byte) (string, error) {
+func (c *PublicAPIClient) extractDataFromPublicAPIResponse(trackerData *config.Tracker, responseJSON []byte) (string, error) {
 	if responseJSON == nil {
-		return "", errors.New("nil response data")
+		return "", &ExtractionError{Path: trackerData.DataExtractionPath}
 	}
 
-	result := gjson.GetBytes(responseJSON, c.trackerData.DataExtractionPath)
+	result := gjson.GetBytes(responseJSON, trackerData.DataExtractionPath)
 	if !result.Exists() {
-		log.Println("[Public API Client] Error extracting data from public API response via the provided JSON path for tracker: "+c.trackerData.Code, "JSON path not found")
+		log.Println("[Public API Client] Error extracting data from public API response via the provided JSON path for tracker: "+trackerData.Code, "JSON path not found")
+
+		return "", &ExtractionError{Path: trackerData.DataExtractionPath}
+	}
 
-		return "", errors.New("json path not found")
+	// Text value trackers take any JSON value as is, e.g. booleans or nested objects
+	if trackerData.GetValueType() == config.ValueTypeText {
+		return strings.Join(strings.Fields(result.String()), " "), nil
 	}
 
 	switch result.Value().(type) {
@@ -84,7 +115,7 @@ func (c *PublicAPIClient) extractDataFromPublicAPIResponse(responseJSON []byte)
 	case float64:
 		return fmt.Sprintf("%f", result.Float()), nil
 	default:
-		log.Println("[Public API Client] Unrecognized extracted response data type for tracker: "+c.trackerData.Code, "Unsupported data type")
-		return "", errors.New("unsupported extracted response data type")
+		log.Println("[Public API Client] Unrecognized extracted response data type for tracker: "+trackerData.Code, "Unsupported data type")
+		return "", &ParseError{Value: result.Raw, Err: errors.New("unsupported extracted response data type")}
 	}
 }
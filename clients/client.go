@@ -1,61 +1,539 @@
 package clients
 
 import (
+	"context"
 	"fmt"
+	"html"
 	"log"
+	"math"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	config "pricetrackerbot/config"
 	"pricetrackerbot/helpers"
+	"pricetrackerbot/services"
+	"pricetrackerbot/utilities"
 )
 
 type DataResult struct {
-	CurrentValue        float64
+	CurrentValue        TrackedValue
 	NotificationMessage string
 }
 
+// Value extracted by a tracker run.
+type TrackedValue struct {
+	Number float64
+	Text   string // Raw extracted text
+	IsText bool   // Whether the tracker works with the text itself instead of the number parsed from it
+	// Of the response the value has been extracted from; sent with the next fetch so that unchanged data is not downloaded again
+	validators services.Validators
+	fetchedAt  time.Time // When the value has been fetched from the data source; zero if unknown
+}
+
+// Formats the value for the status and notification messages.
+func (v TrackedValue) String() string {
+	if v.IsText {
+		return v.Text
+	}
+
+	return fmt.Sprintf("%.2f", v.Number)
+}
+
 // Common client interface that will be implemented by the concrete types of clients.
 type Client interface {
-	FetchAndExtractData(trackerCode string) (*DataResult, error)
+	// Fetches the value of the tracker and evaluates its notification criteria; cancelling the context aborts the fetch.
+	FetchAndExtractData(ctx context.Context, trackerData *config.Tracker, state *NotificationState) (*DataResult, error)
+}
+
+// CriteriaState holds the outcome of a single notification criteria evaluation between tracker runs.
+type CriteriaState struct {
+	Fulfilled    bool
+	LastNotified time.Time
+}
+
+// HistoryPoint is a single value recorded by a past tracker run.
+type HistoryPoint struct {
+	Value     float64
+	Timestamp time.Time
+}
+
+// NotificationState holds everything the notification criteria evaluation needs to remember between the runs of a tracker.
+type NotificationState struct {
+	Criteria      map[string]*CriteriaState
+	PreviousValue *float64 // Value recorded by the previous run
+	BaselineValue *float64 // Value recorded by the first run after the tracker was started
+	PreviousText  *string  // Text recorded by the previous run of a text value tracker
+	BaselineText  *string  // Text recorded by the first run of a text value tracker after it was started
+	AllTimeLow    *float64 // Lowest value ever recorded; only kept while all-time criteria are set
+	AllTimeHigh   *float64 // Highest value ever recorded; only kept while all-time criteria are set
+	// Values recorded within the longest period the historical criteria look back on, oldest first;
+	// loaded from the price history before each run
+	History []HistoryPoint
+	// Full price history the all-time extremes are set from by the next criteria evaluation
+	allTimeHistory []HistoryPoint
+}
+
+func NewNotificationState() *NotificationState {
+	return &NotificationState{Criteria: make(map[string]*CriteriaState)}
 }
 
-// Checks if the extracted value meets the notification criteria set for the tracker
-// and returns a message to be sent to the user if any criteria are met.
-func ProcessNotificationCriteria(trackerData *config.Tracker, extractedValue float64) (string, error) {
+// Returns the state of the given criteria; criteria are identified by their content so that edited criteria start with a fresh state.
+func (s *NotificationState) getCriteriaState(key string) *CriteriaState {
+	if s.Criteria == nil {
+		s.Criteria = make(map[string]*CriteriaState)
+	}
+
+	if _, exists := s.Criteria[key]; !exists {
+		s.Criteria[key] = &CriteriaState{}
+	}
+
+	return s.Criteria[key]
+}
+
+// Identifies a criteria by its content; the key of a group includes the keys of its criteria.
+func criteriaKey(criteria config.NotifyCriteria) string {
+	key := strings.Join([]string{criteria.GetType(), criteria.Operator, criteria.Value, criteria.GetDirection(), criteria.GetReference(), criteria.Period}, "|")
+	if criteria.Operator == config.OperatorBetween {
+		key += "|" + criteria.MaxValue
+	}
+
+	if criteria.GetType() == config.CriteriaTypeGroup {
+		childKeys := make([]string, 0, len(criteria.Criteria))
+		for _, child := range criteria.Criteria {
+			childKeys = append(childKeys, criteriaKey(child))
+		}
+
+		key += "|" + criteria.GetMatch() + "[" + strings.Join(childKeys, ";") + "]"
+	}
+
+	return key
+}
+
+func (s *NotificationState) getReferenceValue(reference string) *float64 {
+	if reference == config.ReferenceBaseline {
+		return s.BaselineValue
+	}
+
+	return s.PreviousValue
+}
+
+func (s *NotificationState) getReferenceText(reference string) *string {
+	if reference == config.ReferenceBaseline {
+		return s.BaselineText
+	}
+
+	return s.PreviousText
+}
+
+// Remembers the extracted value as the reference for the change criteria evaluated in the next runs.
+func (s *NotificationState) recordValue(trackedValue TrackedValue) {
+	if trackedValue.IsText {
+		text := trackedValue.Text
+		s.PreviousText = &text
+		if s.BaselineText == nil {
+			s.BaselineText = &text
+		}
+
+		return
+	}
+
+	value := trackedValue.Number
+	s.PreviousValue = &value
+	if s.BaselineValue == nil {
+		s.BaselineValue = &value
+	}
+
+	// The extremes are only tracked once they have been loaded from the full price history
+	if s.AllTimeLow != nil && value < *s.AllTimeLow {
+		s.AllTimeLow = &value
+	}
+
+	if s.AllTimeHigh != nil && value > *s.AllTimeHigh {
+		s.AllTimeHigh = &value
+	}
+}
+
+// Sets the all-time extremes from the full price history of the tracker once the next value is evaluated.
+func (s *NotificationState) LoadAllTimeExtremes(history []HistoryPoint) {
+	s.AllTimeLow, s.AllTimeHigh = nil, nil
+	s.allTimeHistory = history
+}
+
+/*
+Leaves the value being evaluated out of the loaded price history. The trackers of other chats sharing the fetch of
+the value may have recorded it before the history has been loaded; compared to itself, the value would never be a new
+low or high and would be counted in the moving average.
+*/
+func (s *NotificationState) excludeFetch(fetchedAt time.Time) {
+	if !fetchedAt.IsZero() {
+		s.History = slices.DeleteFunc(s.History, func(point HistoryPoint) bool {
+			return !point.Timestamp.Before(fetchedAt)
+		})
+	}
+
+	if s.allTimeHistory == nil {
+		return
+	}
+
+	for _, point := range s.allTimeHistory {
+		if !fetchedAt.IsZero() && !point.Timestamp.Before(fetchedAt) {
+			continue
+		}
+
+		value := point.Value
+		if s.AllTimeLow == nil || value < *s.AllTimeLow {
+			s.AllTimeLow = &value
+		}
+
+		if s.AllTimeHigh == nil || value > *s.AllTimeHigh {
+			s.AllTimeHigh = &value
+		}
+	}
+
+	s.allTimeHistory = nil
+}
+
+// Returns the values recorded within the given period before now.
+func (s *NotificationState) historySince(period time.Duration, now time.Time) []float64 {
+	values := make([]float64, 0, len(s.History))
+	since := now.Add(-period)
+
+	for _, point := range s.History {
+		if !point.Timestamp.Before(since) {
+			values = append(values, point.Value)
+		}
+	}
+
+	return values
+}
+
+// Returns the longest period the historical criteria look back on and whether any of them need the all-time extremes.
+func RequiredHistory(criteriaList []config.NotifyCriteria) (time.Duration, bool, error) {
+	var period time.Duration
+	allTime := false
+
+	for _, criteria := range criteriaList {
+		switch criteria.GetType() {
+		case config.CriteriaTypeGroup:
+			groupPeriod, groupAllTime, err := RequiredHistory(criteria.Criteria)
+			if err != nil {
+				return 0, false, err
+			}
+
+			period = max(period, groupPeriod)
+			allTime = allTime || groupAllTime
+		case config.CriteriaTypeAllTimeLow, config.CriteriaTypeAllTimeHigh:
+			allTime = true
+		case config.CriteriaTypePeriodLow, config.CriteriaTypePeriodHigh, config.CriteriaTypeMovingAverageCross:
+			criteriaPeriod, err := utilities.ParseDurationWithDays(criteria.Period)
+			if err != nil {
+				return 0, false, err
+			}
+
+			period = max(period, criteriaPeriod)
+		}
+	}
+
+	return period, allTime, nil
+}
+
+/*
+Checks if the extracted value meets the notification criteria set for the tracker and returns a message
+to be sent to the user if any criteria have changed their state:
+  - a criteria that has become fulfilled is always notified about
+  - a criteria that stays fulfilled is notified about again only after the tracker renotify interval has passed
+  - a criteria that is no longer fulfilled is notified about only if the tracker is configured to do so
+
+Once fulfilled, a criteria is considered unfulfilled only after the value moves past the target by more than the hysteresis margin.
+If no state is provided, every fulfilled criteria is notified about.
+*/
+func ProcessNotificationCriteria(trackerData *config.Tracker, extractedValue TrackedValue, state *NotificationState) (string, error) {
+	if state == nil {
+		state = NewNotificationState()
+	}
+
+	renotifyInterval, margin, err := parseNotificationSettings(trackerData)
+	if err != nil {
+		log.Println("[Client] Error parsing notification settings for tracker: "+trackerData.Code, err.Error())
+		return "", &CriteriaError{Err: err}
+	}
+
+	state.excludeFetch(extractedValue.fetchedAt)
+
 	fullfilledCriteria := make([]config.NotifyCriteria, 0)
+	unfulfilledCriteria := make([]config.NotifyCriteria, 0)
+	now := time.Now()
 
 	for _, criteria := range trackerData.NotifyCriteria {
-		notifyValue, err := strconv.ParseFloat(criteria.Value, 64)
-		if err != nil {
-			log.Println("[Client] Error converting notification criteria value for tracker: "+trackerData.Code, err.Error())
-			return "", err
-		}
+		key := criteriaKey(criteria)
+		criteriaState := state.getCriteriaState(key)
 
-		isFulfilled, err := helpers.CompareNumbers(extractedValue, notifyValue, criteria.Operator)
+		isFulfilled, err := evaluateCriteria(criteria, key, extractedValue, state, criteriaState.Fulfilled, margin)
 		if err != nil {
-			log.Println("[Client] Error comparing extracted and notification target values for tracker: "+trackerData.Code, err.Error())
-			return "", err
+			log.Println("[Client] Error evaluating notification criteria for tracker: "+trackerData.Code, err.Error())
+			return "", &CriteriaError{Err: err}
 		}
 
-		if isFulfilled {
+		switch {
+		case isFulfilled && (!criteriaState.Fulfilled || (renotifyInterval > 0 && now.Sub(criteriaState.LastNotified) >= renotifyInterval)):
 			fullfilledCriteria = append(fullfilledCriteria, criteria)
+			criteriaState.LastNotified = now
+		case !isFulfilled && criteriaState.Fulfilled && trackerData.NotifyWhenUnfulfilled:
+			unfulfilledCriteria = append(unfulfilledCriteria, criteria)
 		}
+
+		criteriaState.Fulfilled = isFulfilled
+	}
+
+	state.recordValue(extractedValue)
+
+	if len(fullfilledCriteria) == 0 && len(unfulfilledCriteria) == 0 {
+		return "", nil
 	}
 
+	var builder strings.Builder
 	if len(fullfilledCriteria) > 0 {
-		var builder strings.Builder
 		builder.WriteString(fmt.Sprintf("Good news, tracker <b>%s</b> has detected something you might be interested in :)\n\n", trackerData.Code))
-		builder.WriteString(fmt.Sprintf("The tracked value is currently at <b>%.2f</b> and thus the following criteria are met:\n", extractedValue))
-		for _, criteria := range fullfilledCriteria {
-			operatorEscaped := strings.ReplaceAll(strings.ReplaceAll(criteria.Operator, "<", "&lt;"), ">", "&gt;")
-			builder.WriteString(fmt.Sprintf(" - value: %.2f %s %s\n", extractedValue, operatorEscaped, criteria.Value))
+		builder.WriteString(fmt.Sprintf("The tracked value is currently %s and thus the following criteria are met:\n", describeCurrentValue(extractedValue)))
+		writeCriteriaList(&builder, fullfilledCriteria, extractedValue)
+	}
+
+	if len(unfulfilledCriteria) > 0 {
+		if len(fullfilledCriteria) == 0 {
+			builder.WriteString(fmt.Sprintf("Heads up, tracker <b>%s</b> has detected a change :|\n\n", trackerData.Code))
+			builder.WriteString(fmt.Sprintf("The tracked value is currently %s and thus the following criteria are no longer met:\n", describeCurrentValue(extractedValue)))
+		} else {
+			builder.WriteString("\nThe following criteria are no longer met:\n")
+		}
+
+		writeCriteriaList(&builder, unfulfilledCriteria, extractedValue)
+	}
+
+	builder.WriteString((fmt.Sprintf("\nMore details <a href=\"%s\">here</a>", trackerData.ViewURL)))
+
+	return builder.String(), nil
+}
+
+// Checks whether a single criteria is fulfilled. Criteria comparing the value change are never fulfilled
+// until there is a previous value to compare against.
+func evaluateCriteria(criteria config.NotifyCriteria, key string, trackedValue TrackedValue, state *NotificationState, wasFulfilled bool, margin float64) (bool, error) {
+	if trackedValue.IsText && criteria.GetType() != config.CriteriaTypeGroup {
+		return evaluateTextCriteria(criteria, trackedValue.Text, state)
+	}
+
+	extractedValue := trackedValue.Number
+	criteriaType := criteria.GetType()
+	switch criteriaType {
+	case config.CriteriaTypeGroup:
+		return evaluateGroup(criteria, key, trackedValue, state, margin)
+	case config.CriteriaTypeValue:
+		return compareToCriteriaValue(extractedValue, criteria, wasFulfilled, margin)
+	case config.CriteriaTypeAllTimeLow, config.CriteriaTypeAllTimeHigh,
+		config.CriteriaTypePeriodLow, config.CriteriaTypePeriodHigh, config.CriteriaTypeMovingAverageCross:
+		return evaluateHistoricalCriteria(criteria, extractedValue, state)
+	}
+
+	reference := state.getReferenceValue(criteria.GetReference())
+	if reference == nil {
+		return false, nil
+	}
+
+	delta := extractedValue - *reference
+
+	switch criteriaType {
+	case config.CriteriaTypeAnyChange:
+		return delta != 0, nil
+	case config.CriteriaTypeChange, config.CriteriaTypePercentChange:
+		var amount float64
+		switch criteria.GetDirection() {
+		case config.DirectionUp:
+			amount = delta
+		case config.DirectionDown:
+			amount = -delta
+		default:
+			amount = math.Abs(delta)
+		}
+
+		// The value has moved in the opposite direction
+		if amount < 0 {
+			return false, nil
+		}
+
+		if criteriaType == config.CriteriaTypePercentChange {
+			if *reference == 0 {
+				return false, nil
+			}
+
+			amount = amount / math.Abs(*reference) * 100 //nolint:mnd
+			// The margin is set in the value units, so it is converted to percentage points of the reference like the change itself
+			margin = margin / math.Abs(*reference) * 100 //nolint:mnd
+		}
+
+		return compareToCriteriaValue(amount, criteria, wasFulfilled, margin)
+	default:
+		return false, fmt.Errorf("unsupported notification criteria type: %s", criteriaType)
+	}
+}
+
+// Checks whether a criteria group is fulfilled according to its match mode. All the group criteria are evaluated
+// so that each of them keeps its own state; the group as a whole is notified about as a single criteria.
+func evaluateGroup(group config.NotifyCriteria, groupKey string, extractedValue TrackedValue, state *NotificationState, margin float64) (bool, error) {
+	fulfilledCount := 0
+
+	for _, criteria := range group.Criteria {
+		key := groupKey + "/" + criteriaKey(criteria)
+		criteriaState := state.getCriteriaState(key)
+
+		isFulfilled, err := evaluateCriteria(criteria, key, extractedValue, state, criteriaState.Fulfilled, margin)
+		if err != nil {
+			return false, err
+		}
+
+		criteriaState.Fulfilled = isFulfilled
+		if isFulfilled {
+			fulfilledCount++
+		}
+	}
+
+	if group.GetMatch() == config.MatchAny {
+		return fulfilledCount > 0, nil
+	}
+
+	return fulfilledCount == len(group.Criteria), nil
+}
+
+// Checks a criteria of a text value tracker; equals and contains ignore the letter case, the regular expressions are used as is.
+// The any change criteria is never fulfilled until there is a previous text to compare against.
+func evaluateTextCriteria(criteria config.NotifyCriteria, text string, state *NotificationState) (bool, error) {
+	switch criteria.GetType() {
+	case config.CriteriaTypeEquals:
+		return strings.EqualFold(text, strings.TrimSpace(criteria.Value)), nil
+	case config.CriteriaTypeContains:
+		return strings.Contains(strings.ToLower(text), strings.ToLower(criteria.Value)), nil
+	case config.CriteriaTypeRegex:
+		return regexp.MatchString(criteria.Value, text)
+	case config.CriteriaTypeAnyChange:
+		reference := state.getReferenceText(criteria.GetReference())
+		return reference != nil && *reference != text, nil
+	default:
+		return false, fmt.Errorf("notification criteria type %s cannot be used with text values", criteria.GetType())
+	}
+}
+
+// Checks the criteria comparing the value to the values recorded by the past runs; they are never fulfilled
+// until there is history to compare against. New lows and highs must be strictly beyond the recorded values.
+func evaluateHistoricalCriteria(criteria config.NotifyCriteria, extractedValue float64, state *NotificationState) (bool, error) {
+	switch criteria.GetType() {
+	case config.CriteriaTypeAllTimeLow:
+		return state.AllTimeLow != nil && extractedValue < *state.AllTimeLow, nil
+	case config.CriteriaTypeAllTimeHigh:
+		return state.AllTimeHigh != nil && extractedValue > *state.AllTimeHigh, nil
+	}
+
+	period, err := utilities.ParseDurationWithDays(criteria.Period)
+	if err != nil {
+		return false, err
+	}
+
+	values := state.historySince(period, time.Now())
+	if len(values) == 0 {
+		return false, nil
+	}
+
+	switch criteria.GetType() {
+	case config.CriteriaTypePeriodLow:
+		return extractedValue < slices.Min(values), nil
+	case config.CriteriaTypePeriodHigh:
+		return extractedValue > slices.Max(values), nil
+	default:
+		if state.PreviousValue == nil {
+			return false, nil
+		}
+
+		var sum float64
+		for _, value := range values {
+			sum += value
+		}
+
+		average := sum / float64(len(values))
+		crossedBelow := *state.PreviousValue >= average && extractedValue < average
+		crossedAbove := *state.PreviousValue <= average && extractedValue > average
+
+		switch criteria.GetDirection() {
+		case config.DirectionDown:
+			return crossedBelow, nil
+		case config.DirectionUp:
+			return crossedAbove, nil
+		default:
+			return crossedBelow || crossedAbove, nil
+		}
+	}
+}
+
+// Compares the given value to the criteria value; already fulfilled criteria are compared with the hysteresis margin applied.
+func compareToCriteriaValue(value float64, criteria config.NotifyCriteria, wasFulfilled bool, margin float64) (bool, error) {
+	notifyValue, err := strconv.ParseFloat(criteria.Value, 64)
+	if err != nil {
+		return false, err
+	}
+
+	if criteria.Operator == config.OperatorBetween {
+		maxValue, err := strconv.ParseFloat(criteria.MaxValue, 64)
+		if err != nil {
+			return false, err
+		}
+
+		if !wasFulfilled {
+			margin = 0
 		}
 
-		builder.WriteString((fmt.Sprintf("\nMore details <a href=\"%s\">here</a>", trackerData.ViewURL)))
+		return helpers.IsInRange(value, notifyValue, maxValue, margin), nil
+	}
+
+	if wasFulfilled {
+		return helpers.CompareNumbersWithMargin(value, notifyValue, criteria.Operator, margin)
+	}
 
-		return builder.String(), nil
+	return helpers.CompareNumbers(value, notifyValue, criteria.Operator)
+}
+
+func describeCurrentValue(value TrackedValue) string {
+	if value.IsText {
+		return "<b>" + html.EscapeString(value.Text) + "</b>"
+	}
+
+	return fmt.Sprintf("at <b>%.2f</b>", value.Number)
+}
+
+func writeCriteriaList(builder *strings.Builder, criteriaList []config.NotifyCriteria, extractedValue TrackedValue) {
+	for _, criteria := range criteriaList {
+		if !extractedValue.IsText && criteria.GetType() == config.CriteriaTypeValue && criteria.Operator != config.OperatorBetween {
+			builder.WriteString(fmt.Sprintf(" - value: %.2f %s %s\n", extractedValue.Number, helpers.EscapeOperator(criteria.Operator), criteria.Value))
+		} else {
+			builder.WriteString(" - " + helpers.FormatNotifyCriteria(criteria) + "\n")
+		}
+	}
+}
+
+func parseNotificationSettings(trackerData *config.Tracker) (time.Duration, float64, error) {
+	var renotifyInterval time.Duration
+	var margin float64
+	var err error
+
+	if trackerData.RenotifyInterval != "" {
+		if renotifyInterval, err = utilities.ParseDurationWithDays(trackerData.RenotifyInterval); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if trackerData.Hysteresis != "" {
+		if margin, err = strconv.ParseFloat(trackerData.Hysteresis, 64); err != nil {
+			return 0, 0, err
+		}
 	}
 
-	return "", nil
+	return renotifyInterval, margin, nil
 }
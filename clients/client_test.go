@@ -0,0 +1,144 @@
+package clients
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"pricetrackerbot/config"
+)
+
+// Notification expected from a run of a tracker.
+const (
+	notifyNone  = ""
+	notifyMet   = "met"
+	notifyUnmet = "unmet"
+)
+
+type criteriaRun struct {
+	value   float64
+	elapsed time.Duration // Time passed since the previous notification
+	want    string
+}
+
+func TestProcessNotificationCriteria(t *testing.T) {
+	below100 := []config.NotifyCriteria{{Operator: "<", Value: "100"}}
+
+	tests := []struct {
+		name    string
+		tracker config.Tracker
+		runs    []criteriaRun
+	}{
+		{
+			name:    "notifies once when the criteria becomes fulfilled",
+			tracker: config.Tracker{NotifyCriteria: below100},
+			runs: []criteriaRun{
+				{value: 110, want: notifyNone},
+				{value: 95, want: notifyMet},
+				{value: 90, want: notifyNone},
+				{value: 105, want: notifyNone},
+				{value: 99, want: notifyMet},
+			},
+		},
+		{
+			name:    "notifies about the unfulfilled criteria if configured",
+			tracker: config.Tracker{NotifyCriteria: below100, NotifyWhenUnfulfilled: true},
+			runs: []criteriaRun{
+				{value: 95, want: notifyMet},
+				{value: 105, want: notifyUnmet},
+				{value: 110, want: notifyNone},
+			},
+		},
+		{
+			name:    "renotifies after the interval while the criteria stays fulfilled",
+			tracker: config.Tracker{NotifyCriteria: below100, RenotifyInterval: "1h"},
+			runs: []criteriaRun{
+				{value: 95, want: notifyMet},
+				{value: 94, elapsed: 30 * time.Minute, want: notifyNone},
+				{value: 93, elapsed: time.Hour, want: notifyMet},
+			},
+		},
+		{
+			name:    "hysteresis keeps the criteria fulfilled within the margin",
+			tracker: config.Tracker{NotifyCriteria: below100, Hysteresis: "2", NotifyWhenUnfulfilled: true},
+			runs: []criteriaRun{
+				{value: 99, want: notifyMet},
+				{value: 101, want: notifyNone},
+				{value: 99.5, want: notifyNone},
+				{value: 102.5, want: notifyUnmet},
+				{value: 101, want: notifyNone},
+				{value: 99, want: notifyMet},
+			},
+		},
+		{
+			name:    "hysteresis widens the range of a fulfilled between criteria",
+			tracker: config.Tracker{NotifyCriteria: []config.NotifyCriteria{{Operator: "between", Value: "10", MaxValue: "20"}}, Hysteresis: "1", NotifyWhenUnfulfilled: true},
+			runs: []criteriaRun{
+				{value: 9.5, want: notifyNone},
+				{value: 15, want: notifyMet},
+				{value: 20.5, want: notifyNone},
+				{value: 21.5, want: notifyUnmet},
+			},
+		},
+		{
+			name: "hysteresis of percent change criteria is in value units",
+			tracker: config.Tracker{
+				NotifyCriteria:        []config.NotifyCriteria{{Type: "percentChange", Operator: ">=", Value: "10", Direction: "down", Reference: "baseline"}},
+				Hysteresis:            "5",
+				NotifyWhenUnfulfilled: true,
+			},
+			runs: []criteriaRun{
+				{value: 200, want: notifyNone},
+				{value: 178, want: notifyMet},
+				{value: 182, want: notifyNone},
+				{value: 186, want: notifyUnmet},
+			},
+		},
+		{
+			name:    "change criteria needs a previous value",
+			tracker: config.Tracker{NotifyCriteria: []config.NotifyCriteria{{Type: "percentChange", Operator: ">=", Value: "10", Direction: "down"}}},
+			runs: []criteriaRun{
+				{value: 100, want: notifyNone},
+				{value: 95, want: notifyNone},
+				{value: 85, want: notifyMet},
+				{value: 100, want: notifyNone},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := tt.tracker
+			tracker.Code = "test"
+			state := NewNotificationState()
+
+			for i, run := range tt.runs {
+				for _, criteriaState := range state.Criteria {
+					criteriaState.LastNotified = criteriaState.LastNotified.Add(-run.elapsed)
+				}
+
+				message, err := ProcessNotificationCriteria(&tracker, TrackedValue{Number: run.value}, state)
+				if err != nil {
+					t.Fatalf("run %d: %s", i, err)
+				}
+
+				if got := notificationKind(message); got != run.want {
+					t.Errorf("run %d with value %.2f: notification = %q, want %q", i, run.value, got, run.want)
+				}
+			}
+		})
+	}
+}
+
+func notificationKind(message string) string {
+	switch {
+	case message == "":
+		return notifyNone
+	case strings.Contains(message, "criteria are met"):
+		return notifyMet
+	case strings.Contains(message, "no longer met"):
+		return notifyUnmet
+	default:
+		return message
+	}
+}
@@ -0,0 +1,94 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pricetrackerbot/config"
+	"pricetrackerbot/services"
+)
+
+func TestConditionalFetchReusesLastValue(t *testing.T) {
+	const (
+		etag         = `"v1"`
+		lastModified = "Mon, 19 Oct 2026 10:00:00 GMT"
+	)
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		trackerData *config.Tracker
+		newClient   func(cache *FetchCache) Client
+	}{
+		{
+			name:        "api",
+			body:        `{"price":12.99}`,
+			contentType: "application/json",
+			trackerData: &config.Tracker{Code: "api", DataExtractionPath: "price"},
+			newClient:   func(cache *FetchCache) Client { return NewPublicAPIClient(cache) },
+		},
+		{
+			name:        "scraper",
+			body:        `<html><body><span class="price">12,99 EUR</span></body></html>`,
+			contentType: "text/html",
+			trackerData: &config.Tracker{Code: "scraper", DataExtractionPath: "span.price"},
+			newClient:   func(cache *FetchCache) Client { return NewScraperClient(cache) },
+		},
+	}
+
+	services.SetDefaultClient(services.NewHTTPClient(services.DefaultClientOptions()))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests, notModified int
+			var ifNoneMatch, ifModifiedSince string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/robots.txt" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				requests++
+				ifNoneMatch, ifModifiedSince = r.Header.Get("If-None-Match"), r.Header.Get("If-Modified-Since")
+				if ifNoneMatch == etag {
+					notModified++
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Header().Set("ETag", etag)
+				w.Header().Set("Last-Modified", lastModified)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			trackerData := *tt.trackerData
+			trackerData.DataURL = server.URL + "/price"
+			// Every run fetches the data source, so the last value is only kept for the conditional requests
+			client := tt.newClient(NewFetchCache(0, nil))
+
+			for run := 1; run <= 2; run++ {
+				result, err := client.FetchAndExtractData(context.Background(), &trackerData, NewNotificationState())
+				if err != nil {
+					t.Fatalf("run %d: %s", run, err)
+				}
+
+				if result.CurrentValue.Number != 12.99 {
+					t.Errorf("run %d: value = %.2f, want 12.99", run, result.CurrentValue.Number)
+				}
+			}
+
+			if requests != 2 || notModified != 1 {
+				t.Errorf("requests = %d with %d not modified, want 2 with 1 not modified", requests, notModified)
+			}
+
+			if ifNoneMatch != etag || ifModifiedSince != lastModified {
+				t.Errorf("If-None-Match = %q, If-Modified-Since = %q, want %q, %q", ifNoneMatch, ifModifiedSince, etag, lastModified)
+			}
+		})
+	}
+}
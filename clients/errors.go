@@ -0,0 +1,52 @@
+package clients
+
+import "fmt"
+
+// The data extraction path matches nothing in the fetched data, e.g. after the website has been redesigned.
+type ExtractionError struct {
+	Path string
+}
+
+func (e *ExtractionError) Error() string {
+	return fmt.Sprintf("nothing found by the data extraction path '%s'", e.Path)
+}
+
+// The extracted value is not in the expected format, e.g. a price text without a number.
+type ParseError struct {
+	Value string
+	Err   error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("failed to parse the extracted value '%s': %s", e.Value, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// The notification criteria of the tracker cannot be evaluated, e.g. an invalid regular expression or operator.
+type CriteriaError struct {
+	Err error
+}
+
+func (e *CriteriaError) Error() string {
+	return fmt.Sprintf("misconfigured notification criteria: %s", e.Err)
+}
+
+func (e *CriteriaError) Unwrap() error {
+	return e.Err
+}
+
+// The request spec of the tracker cannot be turned into a request, e.g. an auth secret is not set in the environment.
+type RequestConfigError struct {
+	Err error
+}
+
+func (e *RequestConfigError) Error() string {
+	return fmt.Sprintf("misconfigured request: %s", e.Err)
+}
+
+func (e *RequestConfigError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,176 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	config "pricetrackerbot/config"
+)
+
+/*
+FetchCache shares the values extracted by the trackers reading the same data source, so that a tracker run
+by several chats fetches its URL only once within the cache TTL. Concurrent fetches of the same data source
+wait for the first one to finish instead of sending their own requests. The last value of every data source
+is kept beyond the TTL so that a fetch finding the data unchanged (HTTP 304) can reuse it.
+
+Every fetched value is passed to the recorder once per tracker code, however many chats run the tracker, so that
+the price history has a single record per fetch.
+*/
+type FetchCache struct {
+	ttl        time.Duration
+	recorder   ValueRecorder
+	mu         sync.Mutex
+	entries    map[string]*fetchEntry
+	lastValues map[string]TrackedValue
+}
+
+// Fetches the value of a data source; the last value fetched is passed if there is one, nil otherwise.
+type fetchFunc func(lastValue *TrackedValue) (TrackedValue, error)
+
+// Saves a fetched value of a tracker, e.g. in the price history.
+type ValueRecorder func(trackerData *config.Tracker, value TrackedValue, fetchedAt time.Time)
+
+type fetchEntry struct {
+	done      chan struct{} // Closed once the fetch has finished
+	value     TrackedValue
+	err       error
+	fetchedAt time.Time
+	recorded  map[string]bool // Codes of the trackers the value has been recorded for
+}
+
+// Creates the cache; the recorder is optional.
+func NewFetchCache(ttl time.Duration, recorder ValueRecorder) *FetchCache {
+	return &FetchCache{ttl: ttl, recorder: recorder, entries: make(map[string]*fetchEntry), lastValues: make(map[string]TrackedValue)}
+}
+
+// Returns the value of the data source of the tracker fetched within the TTL or fetches it. A nil cache or a zero TTL
+// always fetches. Cancelling the context stops waiting for the fetch of another tracker; the fetch itself is expected
+// to watch the context too.
+func (c *FetchCache) Get(ctx context.Context, source string, trackerData *config.Tracker, fetch fetchFunc) (TrackedValue, error) {
+	if c == nil {
+		return fetch(nil)
+	}
+
+	key := fetchCacheKey(source, trackerData)
+	if c.ttl <= 0 {
+		entry := &fetchEntry{}
+		entry.value, entry.err = c.fetchAndRemember(key, fetch)
+		entry.fetchedAt = time.Now()
+		entry.value.fetchedAt = entry.fetchedAt
+		c.record(entry, trackerData)
+
+		return entry.value, entry.err
+	}
+
+	c.mu.Lock()
+	if entry, exists := c.entries[key]; exists {
+		select {
+		case <-entry.done:
+			// The trackers waiting for a fetch share its error, but a finished failed fetch is not reused - the next
+			// tracker retries the data source instead of failing on an error it has not seen itself
+			if entry.err == nil && time.Since(entry.fetchedAt) < c.ttl {
+				c.mu.Unlock()
+				c.record(entry, trackerData)
+
+				return entry.value, nil
+			}
+		default:
+			c.mu.Unlock()
+
+			select {
+			case <-entry.done:
+			case <-ctx.Done():
+				return TrackedValue{}, ctx.Err()
+			}
+
+			// A fetch aborted because its tracker has been stopped says nothing about the data source
+			if errors.Is(entry.err, context.Canceled) {
+				return c.Get(ctx, source, trackerData, fetch)
+			}
+
+			c.record(entry, trackerData)
+
+			return entry.value, entry.err
+		}
+	}
+
+	entry := &fetchEntry{done: make(chan struct{})}
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	entry.value, entry.err = c.fetchAndRemember(key, fetch)
+	entry.fetchedAt = time.Now()
+	entry.value.fetchedAt = entry.fetchedAt
+	close(entry.done)
+	c.record(entry, trackerData)
+
+	return entry.value, entry.err
+}
+
+// Passes the successfully fetched value to the recorder unless it has already been recorded for the tracker code,
+// e.g. by the tracker of another chat sharing the fetch.
+func (c *FetchCache) record(entry *fetchEntry, trackerData *config.Tracker) {
+	if c.recorder == nil || entry.err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	if entry.recorded[trackerData.Code] {
+		c.mu.Unlock()
+		return
+	}
+
+	if entry.recorded == nil {
+		entry.recorded = make(map[string]bool)
+	}
+	entry.recorded[trackerData.Code] = true
+	c.mu.Unlock()
+
+	c.recorder(trackerData, entry.value, entry.fetchedAt)
+}
+
+func (c *FetchCache) fetchAndRemember(key string, fetch fetchFunc) (TrackedValue, error) {
+	c.mu.Lock()
+	lastValue, exists := c.lastValues[key]
+	c.mu.Unlock()
+
+	var last *TrackedValue
+	if exists {
+		last = &lastValue
+	}
+
+	value, err := fetch(last)
+
+	c.mu.Lock()
+	if err == nil {
+		c.lastValues[key] = value
+	} else if !errors.Is(err, context.Canceled) {
+		// The next fetch downloads the data in full instead of reusing a value that may not match it anymore
+		delete(c.lastValues, key)
+	}
+	c.mu.Unlock()
+
+	return value, err
+}
+
+// Identifies the data source of a tracker; trackers with the same key extract the same value.
+func fetchCacheKey(source string, trackerData *config.Tracker) string {
+	parts := []string{source, trackerData.DataURL, trackerData.DataExtractionPath, trackerData.GetValueType()}
+
+	// Requests for the same URL may return different data, e.g. searches with different bodies or prices of different regions
+	if trackerData.Request != nil {
+		request, _ := json.Marshal(trackerData.Request)
+		parts = append(parts, string(request))
+	}
+
+	if trackerData.Proxy != nil {
+		proxy, _ := json.Marshal(trackerData.Proxy)
+		parts = append(parts, string(proxy))
+	}
+
+	return strings.Join(parts, "|")
+}
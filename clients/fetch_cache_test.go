@@ -0,0 +1,146 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"pricetrackerbot/config"
+)
+
+func TestFetchCacheRecordsOncePerFetch(t *testing.T) {
+	bonds := &config.Tracker{Code: "bonds", DataURL: "https://example.com/bonds", DataExtractionPath: "price"}
+	bondsCopy := &config.Tracker{Code: "bondsCopy", DataURL: "https://example.com/bonds", DataExtractionPath: "price"}
+
+	tests := []struct {
+		name        string
+		ttl         time.Duration
+		trackers    []*config.Tracker // Trackers getting the value one after another, e.g. in different chats
+		fetchErr    error
+		wantFetches int
+		wantRecords map[string]int
+	}{
+		{
+			name:        "tracker of several chats",
+			ttl:         time.Minute,
+			trackers:    []*config.Tracker{bonds, bonds, bonds},
+			wantFetches: 1,
+			wantRecords: map[string]int{"bonds": 1},
+		},
+		{
+			name:        "trackers sharing the data source",
+			ttl:         time.Minute,
+			trackers:    []*config.Tracker{bonds, bondsCopy, bonds},
+			wantFetches: 1,
+			wantRecords: map[string]int{"bonds": 1, "bondsCopy": 1},
+		},
+		{
+			name:        "no caching",
+			ttl:         0,
+			trackers:    []*config.Tracker{bonds, bonds},
+			wantFetches: 2,
+			wantRecords: map[string]int{"bonds": 2},
+		},
+		{
+			name:        "failed fetches",
+			ttl:         time.Minute,
+			trackers:    []*config.Tracker{bonds, bonds},
+			fetchErr:    errors.New("unavailable"),
+			wantFetches: 2,
+			wantRecords: map[string]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			records := make(map[string]int)
+			cache := NewFetchCache(tt.ttl, func(trackerData *config.Tracker, _ TrackedValue, _ time.Time) {
+				mu.Lock()
+				defer mu.Unlock()
+				records[trackerData.Code]++
+			})
+
+			fetches := 0
+			fetch := func(_ *TrackedValue) (TrackedValue, error) {
+				fetches++
+				return TrackedValue{Number: 42}, tt.fetchErr
+			}
+
+			for _, trackerData := range tt.trackers {
+				if _, err := cache.Get(context.Background(), config.TrackerTypeScraper, trackerData, fetch); !errors.Is(err, tt.fetchErr) {
+					t.Fatalf("Get() error = %v, want %v", err, tt.fetchErr)
+				}
+			}
+
+			if fetches != tt.wantFetches {
+				t.Errorf("fetches = %d, want %d", fetches, tt.wantFetches)
+			}
+
+			if len(records) != len(tt.wantRecords) {
+				t.Errorf("records = %v, want %v", records, tt.wantRecords)
+			}
+
+			for code, want := range tt.wantRecords {
+				if records[code] != want {
+					t.Errorf("records of %s = %d, want %d", code, records[code], want)
+				}
+			}
+		})
+	}
+}
+
+func TestHistoricalCriteriaOfChatsSharingFetch(t *testing.T) {
+	tests := []struct {
+		name     string
+		criteria config.NotifyCriteria
+	}{
+		{name: "period low", criteria: config.NotifyCriteria{Type: config.CriteriaTypePeriodLow, Period: "7d"}},
+		{name: "all-time low", criteria: config.NotifyCriteria{Type: config.CriteriaTypeAllTimeLow}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trackerData := &config.Tracker{Code: "bonds", DataURL: "https://example.com/bonds", NotifyCriteria: []config.NotifyCriteria{tt.criteria}}
+			history := []HistoryPoint{
+				{Value: 100, Timestamp: time.Now().Add(-2 * time.Hour)},
+				{Value: 110, Timestamp: time.Now().Add(-time.Hour)},
+			}
+
+			cache := NewFetchCache(time.Minute, func(_ *config.Tracker, value TrackedValue, fetchedAt time.Time) {
+				history = append(history, HistoryPoint{Value: value.Number, Timestamp: fetchedAt})
+			})
+			fetch := func(_ *TrackedValue) (TrackedValue, error) {
+				return TrackedValue{Number: 90}, nil
+			}
+
+			// Every chat loads the history before its run like the trackers do; the second one sees the value recorded by the first
+			for chat := 1; chat <= 2; chat++ {
+				state := NewNotificationState()
+				state.History = slices.Clone(history)
+				state.LoadAllTimeExtremes(slices.Clone(history))
+
+				value, err := cache.Get(context.Background(), config.TrackerTypeAPI, trackerData, fetch)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				message, err := ProcessNotificationCriteria(trackerData, value, state)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if message == "" {
+					t.Errorf("chat %d has not been notified about the new low", chat)
+				}
+			}
+
+			if len(history) != 3 {
+				t.Errorf("history has %d records, want 3", len(history))
+			}
+		})
+	}
+}
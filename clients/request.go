@@ -0,0 +1,77 @@
+package clients
+
+import (
+	"net/http"
+	"strings"
+
+	config "pricetrackerbot/config"
+	"pricetrackerbot/services"
+)
+
+// Builds the data request of the tracker from its request spec; errors are misconfigurations, e.g. an auth secret not set.
+func buildRequest(trackerData *config.Tracker) (*services.Request, error) {
+	spec := trackerData.Request
+
+	requestURL, err := spec.BuildURL(trackerData.DataURL)
+	if err != nil {
+		return nil, &RequestConfigError{Err: err}
+	}
+
+	headers, err := spec.BuildHeaders()
+	if err != nil {
+		return nil, &RequestConfigError{Err: err}
+	}
+
+	body, contentType := spec.BuildBody()
+	if contentType != "" && !hasHeader(headers, "Content-Type") {
+		headers["Content-Type"] = contentType
+	}
+
+	proxies, err := trackerProxies(trackerData.Proxy)
+	if err != nil {
+		return nil, &RequestConfigError{Err: err}
+	}
+
+	return &services.Request{
+		URL:     requestURL,
+		Method:  spec.GetMethod(),
+		Headers: headers,
+		Body:    body,
+		Timeout: spec.GetTimeout(),
+		Proxies: proxies,
+	}, nil
+}
+
+// Returns the proxy pool of the tracker; nil if the tracker uses the global proxies.
+func trackerProxies(settings *config.ProxySettings) (*services.ProxyPool, error) {
+	switch {
+	case settings == nil:
+		return nil, nil
+	case settings.Disabled:
+		// A pool without proxies sends the requests directly
+		return services.GetDefaultClient().ProxyPool(nil, "")
+	case len(settings.URLs) == 0:
+		return nil, nil
+	default:
+		return services.GetDefaultClient().ProxyPool(settings.URLs, settings.Rotation)
+	}
+}
+
+func toHTTPHeader(headers map[string]string) http.Header {
+	header := make(http.Header, len(headers))
+	for name, value := range headers {
+		header.Set(name, value)
+	}
+
+	return header
+}
+
+func hasHeader(headers map[string]string, name string) bool {
+	for headerName := range headers {
+		if strings.EqualFold(headerName, name) {
+			return true
+		}
+	}
+
+	return false
+}
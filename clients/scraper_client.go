@@ -1,52 +1,158 @@
 package clients
 
 import (
+	"bytes"
+	"context"
 	"errors"
-	"fmt"
 	"log"
+	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/gocolly/colly/v2"
 	config "pricetrackerbot/config"
+	"pricetrackerbot/services"
 )
 
 // Client for fetching data from website HTML's and extracting the necessary data as defined in the tracker configuration.
+// It keeps no state between the calls - every fetch uses its own collector, so that the trackers can share the client.
 type ScraperClient struct {
-	trackerData *config.Tracker
-	collector   *colly.Collector
+	cache *FetchCache
 }
 
-func NewScraperClient() *ScraperClient {
-	return &ScraperClient{collector: colly.NewCollector(colly.AllowURLRevisit())}
+func NewScraperClient(cache *FetchCache) *ScraperClient {
+	return &ScraperClient{cache: cache}
 }
 
-func (c *ScraperClient) FetchAndExtractData(trackerData *config.Tracker) (*DataResult, error) {
-	var price string
-	var executionError error
-	c.trackerData = trackerData
+func (c *ScraperClient) FetchAndExtractData(ctx context.Context, trackerData *config.Tracker, state *NotificationState) (*DataResult, error) {
+	trackedValue, err := c.cache.Get(ctx, config.TrackerTypeScraper, trackerData, func(lastValue *TrackedValue) (TrackedValue, error) {
+		return c.fetchValue(ctx, trackerData, lastValue)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notification, err := ProcessNotificationCriteria(trackerData, trackedValue, state)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataResult{
+		CurrentValue:        trackedValue,
+		NotificationMessage: notification,
+	}, nil
+}
+
+// Result of a scraping request collected by the collector callbacks.
+type scrapeResult struct {
+	text       string
+	validators services.Validators
+	err        error
+}
 
-	// On every a element which has href attribute call callback
-	c.collector.OnHTML(c.trackerData.DataExtractionPath, func(e *colly.HTMLElement) {
-		price = e.Text
+func (c *ScraperClient) fetchValue(ctx context.Context, trackerData *config.Tracker, lastValue *TrackedValue) (TrackedValue, error) {
+	request, err := buildRequest(trackerData)
+	if err != nil {
+		return TrackedValue{}, err
+	}
+
+	httpClient := services.GetDefaultClient()
+	politeness := httpClient.Politeness()
+	// The robots.txt is checked on every run as well since the website may change it while the tracker runs
+	if err := politeness.CheckRobots(ctx, request.URL); err != nil {
+		return TrackedValue{}, err
+	}
+
+	parsedURL, err := url.Parse(request.URL)
+	if err != nil {
+		return TrackedValue{}, &RequestConfigError{Err: err}
+	}
+
+	header := toHTTPHeader(request.Headers)
+	if header.Get("User-Agent") == "" {
+		header.Set("User-Agent", httpClient.UserAgent())
+	}
+
+	if lastValue != nil && services.CanBeConditional(request.Method) {
+		lastValue.validators.AddHeaders(header)
+	}
+
+	// Waits for the turn of the domain so that all the trackers of a website together respect its limits
+	release, err := politeness.Acquire(ctx, parsedURL.Hostname())
+	if err != nil {
+		return TrackedValue{}, services.ClassifyRequestError(request.URL, err)
+	}
+
+	log.Println("[Scraper Client] Making a scraping request for tracker: " + trackerData.Code)
+	result := c.scrape(ctx, trackerData, request, header)
+	release()
+
+	if errors.Is(result.err, services.ErrNotModified) && lastValue != nil {
+		log.Println("[Scraper Client] Page not modified since the last fetch for tracker: " + trackerData.Code)
+		return *lastValue, nil
+	}
+
+	if result.err != nil {
+		return TrackedValue{}, result.err
+	}
+
+	if trackerData.GetValueType() == config.ValueTypeText {
+		// A missing element is a valid state for the text trackers, e.g. an "Add to cart" button disappearing when out of stock
+		return TrackedValue{Text: strings.Join(strings.Fields(result.text), " "), IsText: true, validators: result.validators}, nil
+	}
+
+	priceFloat, err := parsePrice(trackerData, result.text)
+	if err != nil {
+		return TrackedValue{}, err
+	}
+
+	return TrackedValue{Number: priceFloat, Text: strings.TrimSpace(result.text), validators: result.validators}, nil
+}
+
+// Sends the scraping request with a collector of its own, so that the callbacks of the previous requests do not pile up.
+func (c *ScraperClient) scrape(ctx context.Context, trackerData *config.Tracker, request *services.Request, header http.Header) scrapeResult {
+	// Ends the requests of the collector once the scraping is done, whether or not the tracker is stopped
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var result scrapeResult
+
+	// The shared client limits the body size itself and fails instead of truncating the page
+	collector := colly.NewCollector(colly.AllowURLRevisit(), colly.MaxBodySize(0))
+	collector.SetClient(services.GetDefaultClient().StandardClient(ctx, request.Timeout, request.Proxies))
+
+	collector.OnHTML(trackerData.DataExtractionPath, func(e *colly.HTMLElement) {
+		result.text = e.Text
 	})
 
-	c.collector.OnError(func(_ *colly.Response, err error) {
-		log.Printf("[Scraper Client] Error while making scraping request for tracker %s: %s", c.trackerData.Code, err.Error())
-		executionError = err
+	collector.OnResponse(func(response *colly.Response) {
+		result.validators = services.ValidatorsFromHeader(*response.Headers)
 	})
 
-	log.Println("[Scraper Client] Making a scraping request for tracker: " + c.trackerData.Code)
-	executionError = c.collector.Visit(trackerData.DataURL)
+	collector.OnError(func(response *colly.Response, err error) {
+		if response != nil && response.StatusCode == http.StatusNotModified {
+			result.err = services.ErrNotModified
+			return
+		}
+
+		log.Printf("[Scraper Client] Error while making scraping request for tracker %s: %s", trackerData.Code, err.Error())
+		result.err = classifyScrapingError(request.URL, response, err)
+	})
 
-	if executionError != nil {
-		return nil, executionError
+	if err := collector.Request(request.Method, request.URL, bytes.NewReader(request.Body), nil, header); err != nil && result.err == nil {
+		result.err = services.ClassifyRequestError(request.URL, err)
 	}
 
+	return result
+}
+
+// Extracts the number from the scraped text, e.g. "12,99 EUR".
+func parsePrice(trackerData *config.Tracker, price string) (float64, error) {
 	if price == "" {
-		log.Println("[Scraper Client] Price value not found in the scraped HTML element for tracker: " + c.trackerData.Code)
-		return nil, errors.New("price not found")
+		log.Println("[Scraper Client] Price value not found in the scraped HTML element for tracker: " + trackerData.Code)
+		return 0, &ExtractionError{Path: trackerData.DataExtractionPath}
 	}
 
 	reg := regexp.MustCompile(`[^0-9.,]`)
@@ -56,18 +162,17 @@ func (c *ScraperClient) FetchAndExtractData(trackerData *config.Tracker) (*DataR
 	priceFloat, err := strconv.ParseFloat(cleanPrice, 64)
 	if err != nil {
 		log.Printf("[Scraper Client] Failed to parse scraped value for tracker %s: %s", trackerData.Code, err.Error())
-		return nil, fmt.Errorf("failed to parse price: %w", err)
+		return 0, &ParseError{Value: strings.TrimSpace(price), Err: err}
 	}
 
-	notification, err := ProcessNotificationCriteria(c.trackerData, priceFloat)
-	if err != nil {
-		return nil, err
-	}
+	return priceFloat, nil
+}
 
-	c.trackerData = nil
+// Colly reports error responses as errors too; those are told apart from the failures of sending the request.
+func classifyScrapingError(url string, response *colly.Response, err error) error {
+	if response != nil && response.StatusCode >= http.StatusMultipleChoices {
+		return &services.HTTPStatusError{URL: url, StatusCode: response.StatusCode}
+	}
 
-	return &DataResult{
-		CurrentValue:        priceFloat,
-		NotificationMessage: notification,
-	}, nil
+	return services.ClassifyRequestError(url, err)
 }
@@ -1,38 +1,257 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"log"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/joho/godotenv"
+	"github.com/robfig/cron/v3"
+	"pricetrackerbot/utilities"
+)
+
+const (
+	TrackerTypeAPI     = "api"
+	TrackerTypeScraper = "scraper"
+)
+
+// Format of the active window times.
+const ClockFormat = "15:04"
+
+const (
+	apiTrackersFileVar     = "API_TRACKERS_FILE"
+	scraperTrackersFileVar = "SCRAPER_TRACKERS_FILE"
+)
+
+// Notification criteria types.
+const (
+	CriteriaTypeValue              = "value"              // The value compared to a fixed number
+	CriteriaTypeChange             = "change"             // The absolute change of the value compared to a fixed number
+	CriteriaTypePercentChange      = "percentChange"      // The change of the value in percent compared to a fixed number
+	CriteriaTypeAnyChange          = "anyChange"          // Any change of the value
+	CriteriaTypeAllTimeLow         = "allTimeLow"         // The value is lower than ever recorded
+	CriteriaTypeAllTimeHigh        = "allTimeHigh"        // The value is higher than ever recorded
+	CriteriaTypePeriodLow          = "periodLow"          // The value is lower than recorded within the period
+	CriteriaTypePeriodHigh         = "periodHigh"         // The value is higher than recorded within the period
+	CriteriaTypeMovingAverageCross = "movingAverageCross" // The value has crossed the moving average of the period
+	CriteriaTypeGroup              = "group"              // A group of criteria combined with the group match mode
+	CriteriaTypeEquals             = "equals"             // The text value equals the criteria value
+	CriteriaTypeContains           = "contains"           // The text value contains the criteria value
+	CriteriaTypeRegex              = "regex"              // The text value matches the criteria value regular expression
+)
+
+// Types of the values extracted by the trackers.
+const (
+	ValueTypeNumber = "number" // The extracted value is parsed as a number
+	ValueTypeText   = "text"   // The extracted text is used as is, e.g. for tracking availability
+)
+
+// Operator comparing the value to the range between the criteria value and max value, both inclusive.
+const OperatorBetween = "between"
+
+// Match modes of the criteria groups.
+const (
+	MatchAll = "all" // All of the group criteria must be fulfilled
+	MatchAny = "any" // At least one of the group criteria must be fulfilled
+)
+
+// Directions of the value change the change and moving average criteria apply to.
+const (
+	DirectionAny  = "any"
+	DirectionUp   = "up"
+	DirectionDown = "down"
+)
+
+// Values the change criteria compare the current value against.
+const (
+	ReferencePrevious = "previous" // The value recorded by the previous tracker run
+	ReferenceBaseline = "baseline" // The value recorded by the first run after the tracker was started
 )
 
 type NotifyCriteria struct {
-	Operator string `json:"operator" validate:"required,oneof='<=' '<' '=' '>=' '>'"`
-	Value    string `json:"value" validate:"required,numeric"`
+	Type      string           `json:"type,omitempty" validate:"omitempty,oneof=value change percentChange anyChange allTimeLow allTimeHigh periodLow periodHigh movingAverageCross group equals contains regex"`
+	Operator  string           `json:"operator" validate:"omitempty,oneof='<=' '<' '=' '>=' '>' 'between'"`
+	Value     string           `json:"value"`
+	MaxValue  string           `json:"maxValue,omitempty" validate:"omitempty,numeric"` // Upper bound of the 'between' operator range
+	Direction string           `json:"direction,omitempty" validate:"omitempty,oneof=any up down"`
+	Reference string           `json:"reference,omitempty" validate:"omitempty,oneof=previous baseline"`
+	Period    string           `json:"period,omitempty" validate:"omitempty,interval"`
+	Match     string           `json:"match,omitempty" validate:"omitempty,oneof=all any"` // How the criteria of a group are combined; all by default
+	Criteria  []NotifyCriteria `json:"criteria,omitempty" validate:"dive"`                 // Criteria of a group
+}
+
+// Fields required depend on the criteria type; the rest is validated with the field tags.
+func validateNotifyCriteriaFields(sl validator.StructLevel) {
+	criteria, ok := sl.Current().Interface().(NotifyCriteria)
+	if !ok {
+		return
+	}
+
+	switch criteria.GetType() {
+	case CriteriaTypeValue, CriteriaTypeChange, CriteriaTypePercentChange:
+		if criteria.Operator == "" {
+			sl.ReportError(criteria.Operator, "Operator", "Operator", "required", "")
+		}
+
+		if criteria.Value == "" {
+			sl.ReportError(criteria.Value, "Value", "Value", "required", "")
+		} else if sl.Validator().Var(criteria.Value, "numeric") != nil {
+			sl.ReportError(criteria.Value, "Value", "Value", "numeric", "")
+		}
+
+		if criteria.Operator == OperatorBetween {
+			minValue, minErr := strconv.ParseFloat(criteria.Value, 64)
+			maxValue, maxErr := strconv.ParseFloat(criteria.MaxValue, 64)
+
+			if criteria.MaxValue == "" {
+				sl.ReportError(criteria.MaxValue, "MaxValue", "MaxValue", "required", "")
+			} else if minErr == nil && maxErr == nil && maxValue < minValue {
+				sl.ReportError(criteria.MaxValue, "MaxValue", "MaxValue", "gtefield", "Value")
+			}
+		}
+	case CriteriaTypeEquals, CriteriaTypeContains, CriteriaTypeRegex:
+		if criteria.Value == "" {
+			sl.ReportError(criteria.Value, "Value", "Value", "required", "")
+		} else if _, err := regexp.Compile(criteria.Value); criteria.GetType() == CriteriaTypeRegex && err != nil {
+			sl.ReportError(criteria.Value, "Value", "Value", "regexp", "")
+		}
+	case CriteriaTypeGroup:
+		if len(criteria.Criteria) == 0 {
+			sl.ReportError(criteria.Criteria, "Criteria", "Criteria", "required", "")
+		}
+	case CriteriaTypePeriodLow, CriteriaTypePeriodHigh, CriteriaTypeMovingAverageCross:
+		if criteria.Period == "" {
+			sl.ReportError(criteria.Period, "Period", "Period", "required", "")
+		}
+	}
+}
+
+// Returns the criteria type; criteria without a type compare the value itself.
+func (nc NotifyCriteria) GetType() string {
+	if nc.Type == "" {
+		return CriteriaTypeValue
+	}
+
+	return nc.Type
+}
+
+func (nc NotifyCriteria) GetDirection() string {
+	if nc.Direction == "" {
+		return DirectionAny
+	}
+
+	return nc.Direction
+}
+
+// Whether the criteria compares text values; such criteria can only be used by the text value trackers.
+func (nc NotifyCriteria) IsTextCriteria() bool {
+	switch nc.GetType() {
+	case CriteriaTypeEquals, CriteriaTypeContains, CriteriaTypeRegex:
+		return true
+	default:
+		return false
+	}
+}
+
+func (nc NotifyCriteria) GetMatch() string {
+	if nc.Match == "" {
+		return MatchAll
+	}
+
+	return nc.Match
+}
+
+func (nc NotifyCriteria) GetReference() string {
+	if nc.Reference == "" {
+		return ReferencePrevious
+	}
+
+	return nc.Reference
 }
 
 type Tracker struct {
-	Code               string           `json:"code" validate:"required,excludesall=_/ "`
-	DataURL            string           `json:"dataUrl" validate:"required,url"`
-	ViewURL            string           `json:"viewUrl" validate:"omitempty,url"`
-	Interval           string           `json:"interval" validate:"required"`
-	NotifyCriteria     []NotifyCriteria `json:"notifyCriteria" validate:"dive"`
-	DataExtractionPath string           `json:"dataExtractionPath" validate:"required"`
+	Code                  string           `json:"code" validate:"required,excludesall=_/ "`
+	DataURL               string           `json:"dataUrl" validate:"required,url"`
+	ViewURL               string           `json:"viewUrl" validate:"omitempty,url"`
+	Interval              string           `json:"interval" validate:"required,interval"`
+	NotifyCriteria        []NotifyCriteria `json:"notifyCriteria" validate:"dive"`
+	DataExtractionPath    string           `json:"dataExtractionPath" validate:"required"`
+	RenotifyInterval      string           `json:"renotifyInterval" validate:"omitempty,interval"` // How often to repeat the notification while a criteria stays fulfilled; never if empty
+	NotifyWhenUnfulfilled bool             `json:"notifyWhenUnfulfilled"`                          // Whether to notify when a fulfilled criteria is no longer fulfilled
+	Hysteresis            string           `json:"hysteresis" validate:"omitempty,numeric"`        // How far the value must move past the target before a fulfilled criteria becomes unfulfilled
+	ValueType             string           `json:"valueType,omitempty" validate:"omitempty,oneof=number text"`
+	Schedule              *TrackerSchedule `json:"schedule,omitempty"` // When the tracker runs; every interval if not set
+	Request               *RequestSpec     `json:"request,omitempty"`  // How the data is requested; a plain GET request if not set
+	Proxy                 *ProxySettings   `json:"proxy,omitempty"`    // Proxies of the tracker requests; the global proxies if not set
+}
+
+// Run schedule of a tracker. Cron expressions replace the interval runs unless the interval has been set via the
+// /interval command; active windows limit both to certain times of the day. Times are in the TZ timezone.
+type TrackerSchedule struct {
+	Cron    []string       `json:"cron,omitempty" validate:"dive,cron"`
+	Windows []ActiveWindow `json:"windows,omitempty" validate:"dive"` // The tracker runs at any time if there are none
+}
+
+// A time of the day the tracker is active in, e.g. 08:00-22:00. Windows ending before they start cross midnight.
+type ActiveWindow struct {
+	Days []string `json:"days,omitempty" validate:"dive,oneof=mon tue wed thu fri sat sun"` // Every day if empty
+	From string   `json:"from" validate:"required,clock"`
+	To   string   `json:"to" validate:"required,clock,nefield=From"`
+}
+
+func (t *Tracker) GetValueType() string {
+	if t.ValueType == "" {
+		return ValueTypeNumber
+	}
+
+	return t.ValueType
 }
 
 type Configuration struct {
-	BotAPIKey        string     `validate:"required"`
-	WebhookURL       string     `validate:"required,url"`
-	Port             string     `validate:"omitempty,numeric"`
-	Environment      string     `validate:"required"`
-	ErrorNotifyLimit int        `validate:"omitempty,numeric"`
-	APITrackers      []*Tracker `validate:"dive"`
-	ScraperTrackers  []*Tracker `validate:"dive"`
+	BotAPIKey                 string            `validate:"required"`
+	WebhookURL                string            `validate:"required,url"`
+	Port                      string            `validate:"omitempty,numeric"`
+	Environment               string            `validate:"required"`
+	ErrorNotifyLimit          int               `validate:"omitempty,numeric"`
+	StorageFile               string            `validate:"required"`
+	TrackerFilesWatchInterval time.Duration     // How often the tracker configuration files are checked for changes; 0 disables watching
+	FetchCacheTTL             time.Duration     // How long a fetched value is shared with the other trackers of the same data source; 0 disables sharing
+	AllowedUserIDs            []int64           // Telegram users allowed to use the bot; everyone is allowed if no users, chats or admins are set
+	AllowedChatIDs            []int64           // Chats, e.g. groups, whose members are allowed to use the bot
+	AdminUserIDs              []int64           // Users allowed to use the admin commands; nobody can use them if not set
+	SchedulerWorkers          int               // How many tracker runs may execute at the same time
+	HostConcurrency           int               // How many tracker runs may send requests to the same host at the same time
+	HostMinSpacing            time.Duration     // Minimum time between the starts of tracker runs sending requests to the same host
+	SchedulerMaxJitter        time.Duration     // Maximum random delay added to every planned tracker run
+	RetryAttempts             int               // How many times a failed fetch is retried within a tracker run
+	RetryDelay                time.Duration     // Delay before the first retry; doubled for every next one
+	FailureBackoffMax         time.Duration     // Maximum delay added to the run interval after consecutive failed runs; 0 disables it
+	AutoPauseThreshold        int               // Consecutive failed runs after which a tracker is paused; 0 disables pausing
+	ErrorHistorySize          int               // How many of the most recent execution errors are kept per tracker
+	HTTPTimeout               time.Duration     // Default timeout of the tracker requests, including their redirects and retries
+	HTTPMaxRedirects          int               // Redirects followed by the tracker requests
+	HTTPMaxBodySize           int               // Maximum response size in bytes; 0 disables the limit
+	HTTPRetryAttempts         int               // Retries of the requests answered with 429 or 5xx
+	HTTPRetryMaxWait          time.Duration     // Longest Retry-After wait honored
+	UserAgent                 string            // Sent with the tracker requests not setting their own
+	ProxyURLs                 []string          // Proxies of the tracker requests without their own proxy settings
+	ProxyRotation             string            // How the proxy of a request is picked: round-robin or sticky per host
+	ScraperMinDelay           time.Duration     // Minimum time between the scraping requests to the same domain
+	ScraperMaxParallel        int               // How many scraping requests may be sent to the same domain at the same time; 0 for no limit
+	ObeyRobotsTxt             bool              // Whether the pages disallowed by the robots.txt of the websites are not scraped
+	RobotsCacheTTL            time.Duration     // How long a fetched robots.txt is used before it is fetched again
+	DomainSettings            []*DomainSettings `validate:"dive"` // Politeness settings replacing the defaults for specific domains
+	APITrackers               []*Tracker        `validate:"dive"`
+	ScraperTrackers           []*Tracker        `validate:"dive"`
+	trackersMu                sync.RWMutex      // Trackers can be added and removed at runtime
 }
 
 var config *Configuration
@@ -60,21 +279,86 @@ func GetConfig() *Configuration {
 			config.ErrorNotifyLimit = 3
 		}
 
-		config.APITrackers, err = loadTrackers("API_TRACKERS_FILE")
+		config.StorageFile = os.Getenv("STORAGE_FILE")
+		if config.StorageFile == "" {
+			config.StorageFile = "data/price_tracker.db"
+		}
+
+		config.TrackerFilesWatchInterval = getDurationEnv("TRACKER_FILES_WATCH_INTERVAL", 30*time.Second) //nolint:mnd
+		config.FetchCacheTTL = getDurationEnv("FETCH_CACHE_TTL", 30*time.Second)                          //nolint:mnd
+		config.SchedulerWorkers = getIntEnv("SCHEDULER_WORKERS", 4, 1)                                    //nolint:mnd
+		config.HostConcurrency = getIntEnv("HOST_CONCURRENCY", 2, 1)                                      //nolint:mnd
+		config.HostMinSpacing = getDurationEnv("HOST_MIN_SPACING", 2*time.Second)                         //nolint:mnd
+		config.SchedulerMaxJitter = getDurationEnv("SCHEDULER_MAX_JITTER", 5*time.Second)                 //nolint:mnd
+		config.RetryAttempts = getIntEnv("RETRY_ATTEMPTS", 2, 0)                                          //nolint:mnd
+		config.RetryDelay = getDurationEnv("RETRY_DELAY", 5*time.Second)                                  //nolint:mnd
+		config.FailureBackoffMax = getDurationEnv("FAILURE_BACKOFF_MAX", 6*time.Hour)                     //nolint:mnd
+		config.AutoPauseThreshold = getIntEnv("AUTO_PAUSE_THRESHOLD", 10, 0)                              //nolint:mnd
+		config.ErrorHistorySize = getIntEnv("ERROR_HISTORY_SIZE", 20, 1)                                  //nolint:mnd
+		config.HTTPTimeout = getDurationEnv("HTTP_TIMEOUT", 15*time.Second)                               //nolint:mnd
+		config.HTTPMaxRedirects = getIntEnv("HTTP_MAX_REDIRECTS", 5, 0)                                   //nolint:mnd
+		config.HTTPMaxBodySize = getIntEnv("HTTP_MAX_BODY_SIZE", 10<<20, 0)                               //nolint:mnd
+		config.HTTPRetryAttempts = getIntEnv("HTTP_RETRY_ATTEMPTS", 2, 0)                                 //nolint:mnd
+		config.HTTPRetryMaxWait = getDurationEnv("HTTP_RETRY_MAX_WAIT", 30*time.Second)                   //nolint:mnd
+
+		config.UserAgent = os.Getenv("USER_AGENT")
+		if config.UserAgent == "" {
+			config.UserAgent = "Mozilla/5.0 (compatible; pricetrackerbot)"
+		}
+
+		config.ProxyURLs = parseList(os.Getenv("PROXY_URLS"))
+		for _, proxyURL := range config.ProxyURLs {
+			if !isProxyURL(proxyURL) {
+				log.Fatalf("[GetConfig] Invalid PROXY_URLS value: %s", proxyURL)
+			}
+		}
+
+		config.ProxyRotation = os.Getenv("PROXY_ROTATION")
+		if config.ProxyRotation != "" && config.ProxyRotation != ProxyRotationRoundRobin && config.ProxyRotation != ProxyRotationSticky {
+			log.Fatalf("[GetConfig] Invalid PROXY_ROTATION value: %s", config.ProxyRotation)
+		}
+
+		config.ScraperMinDelay = getDurationEnv("SCRAPER_MIN_DELAY", 0)
+		config.ScraperMaxParallel = getIntEnv("SCRAPER_MAX_PARALLEL", 0, 0)
+		config.ObeyRobotsTxt = getBoolEnv("OBEY_ROBOTS_TXT", true)
+		config.RobotsCacheTTL = getDurationEnv("ROBOTS_CACHE_TTL", 24*time.Hour) //nolint:mnd
+
+		config.DomainSettings, err = loadDomainSettings()
+		if err != nil {
+			log.Fatalf("[GetConfig] Error loading domain settings: %v", err)
+		}
+
+		for envVar, ids := range map[string]*[]int64{"ALLOWED_USER_IDS": &config.AllowedUserIDs, "ALLOWED_CHAT_IDS": &config.AllowedChatIDs, "ADMIN_USER_IDS": &config.AdminUserIDs} {
+			if *ids, err = parseIDList(os.Getenv(envVar)); err != nil {
+				log.Fatalf("[GetConfig] Invalid %s value: %v", envVar, err)
+			}
+		}
+
+		if len(config.AllowedUserIDs) == 0 && len(config.AllowedChatIDs) == 0 && len(config.AdminUserIDs) == 0 {
+			log.Println("[GetConfig] No allowed users or chats configured; the bot can be used by anyone")
+		}
+
+		if len(config.AdminUserIDs) == 0 {
+			log.Println("[GetConfig] No admins configured; the admin commands are disabled")
+		}
+
+		config.APITrackers, err = loadTrackers(apiTrackersFileVar)
 		if err != nil {
 			log.Fatalf("[GetConfig] Error loading API trackers: %v", err)
 		}
 
-		config.ScraperTrackers, err = loadTrackers("SCRAPER_TRACKERS_FILE")
+		config.ScraperTrackers, err = loadTrackers(scraperTrackersFileVar)
 		if err != nil {
 			log.Fatalf("[GetConfig] Error loading scraper trackers: %v", err)
 		}
 
 		if len(config.APITrackers) == 0 && len(config.ScraperTrackers) == 0 {
-			log.Fatalf("[GetConfig] No trackers defined in the configuration")
+			log.Println("[GetConfig] No trackers defined in the configuration files; trackers can still be added via the bot")
 		}
 
-		config.ValidateConfig()
+		if err := config.ValidateConfig(); err != nil {
+			log.Fatalf("[GetConfig] Config validation error: %v", err)
+		}
 
 		// For debugging purposes
 		// configJSON, err := json.MarshalIndent(config, "", "  ")
@@ -93,12 +377,12 @@ func loadTrackers(fileVar string) ([]*Tracker, error) {
 	if filePath != "" {
 		data, err := os.ReadFile(filePath)
 		if err != nil {
-			return nil, errors.New("failed to read tracker file")
+			return nil, fmt.Errorf("failed to read tracker file: %w", err)
 		}
 
 		var trackers []*Tracker
 		if err := json.Unmarshal(data, &trackers); err != nil {
-			return nil, errors.New("failed to parse JSON from file")
+			return nil, fmt.Errorf("failed to parse JSON from file: %w", err)
 		}
 
 		return trackers, nil
@@ -107,14 +391,336 @@ func loadTrackers(fileVar string) ([]*Tracker, error) {
 	return nil, nil
 }
 
-func (c *Configuration) ValidateConfig() {
-	validate := validator.New()
+func (c *Configuration) ValidateConfig() error {
+	validate := newValidator()
 	if err := validate.Struct(c); err != nil {
-		log.Fatalf("[GetConfig] Config validation error: %v", err)
+		return err
 	}
+
+	allTrackers := append(c.GetAPITrackers(), c.GetScraperTrackers()...)
+	for _, tracker := range allTrackers {
+		if err := ValidateTracker(tracker); err != nil {
+			return fmt.Errorf("invalid tracker '%s': %w", tracker.Code, err)
+		}
+	}
+
+	return validateUniqueCodes(allTrackers)
+}
+
+// Reads and validates the tracker configuration files without modifying the current configuration.
+func LoadTrackerFiles() ([]*Tracker, []*Tracker, error) {
+	apiTrackers, err := loadTrackers(apiTrackersFileVar)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading API trackers: %w", err)
+	}
+
+	scraperTrackers, err := loadTrackers(scraperTrackersFileVar)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading scraper trackers: %w", err)
+	}
+
+	allTrackers := append(append([]*Tracker(nil), apiTrackers...), scraperTrackers...)
+	for _, tracker := range allTrackers {
+		if err := ValidateTracker(tracker); err != nil {
+			return nil, nil, fmt.Errorf("invalid tracker '%s': %w", tracker.Code, err)
+		}
+	}
+
+	if err := validateUniqueCodes(allTrackers); err != nil {
+		return nil, nil, err
+	}
+
+	return apiTrackers, scraperTrackers, nil
+}
+
+// Reads a duration, e.g. "30s", from an environment variable; exits on invalid values.
+func getDurationEnv(envVar string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(envVar)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("[GetConfig] Invalid %s value: %s", envVar, value)
+	}
+
+	return duration
+}
+
+// Reads a number not lower than the minimum value from an environment variable; exits on invalid values.
+func getIntEnv(envVar string, defaultValue int, minValue int) int {
+	value := os.Getenv(envVar)
+	if value == "" {
+		return defaultValue
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < minValue {
+		log.Fatalf("[GetConfig] Invalid %s value: %s", envVar, value)
+	}
+
+	return number
+}
+
+// Reads a boolean, e.g. "true" or "0", from an environment variable; exits on invalid values.
+func getBoolEnv(envVar string, defaultValue bool) bool {
+	value := os.Getenv(envVar)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("[GetConfig] Invalid %s value: %s", envVar, value)
+	}
+
+	return parsed
+}
+
+// Splits a comma separated list, e.g. "a, b", dropping the empty values.
+func parseList(value string) []string {
+	values := make([]string, 0)
+
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+
+	return values
+}
+
+// Parses a comma separated list of Telegram user or chat IDs, e.g. "12345, -100987".
+func parseIDList(value string) ([]int64, error) {
+	ids := make([]int64, 0)
+
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func validateUniqueCodes(trackers []*Tracker) error {
+	codes := make(map[string]bool)
+	for _, tracker := range trackers {
+		if codes[tracker.Code] {
+			return fmt.Errorf("duplicate tracker code '%s'", tracker.Code)
+		}
+
+		codes[tracker.Code] = true
+	}
+
+	return nil
+}
+
+// Replaces all the configured trackers, e.g. after the tracker configuration files have been reloaded.
+func (c *Configuration) ReplaceTrackers(apiTrackers []*Tracker, scraperTrackers []*Tracker) {
+	c.trackersMu.Lock()
+	defer c.trackersMu.Unlock()
+
+	c.APITrackers = apiTrackers
+	c.ScraperTrackers = scraperTrackers
+}
+
+// Periodically checks the tracker configuration files for changes and calls onChange whenever any of them is modified.
+// Blocks until the context is cancelled.
+func (c *Configuration) WatchTrackerFiles(ctx context.Context, onChange func()) {
+	if c.TrackerFilesWatchInterval <= 0 {
+		return
+	}
+
+	filePaths := []string{os.Getenv(apiTrackersFileVar), os.Getenv(scraperTrackersFileVar)}
+	lastVersion := trackerFilesVersion(filePaths)
+
+	ticker := time.NewTicker(c.TrackerFilesWatchInterval)
+	defer ticker.Stop()
+
+	log.Printf("[Config] Watching tracker configuration files for changes every %s", c.TrackerFilesWatchInterval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if version := trackerFilesVersion(filePaths); version != lastVersion {
+				log.Println("[Config] Tracker configuration file change detected")
+				lastVersion = version
+				onChange()
+			}
+		}
+	}
+}
+
+// Builds a string that changes whenever any of the files is modified, created or deleted.
+func trackerFilesVersion(filePaths []string) string {
+	var builder strings.Builder
+	for _, filePath := range filePaths {
+		if filePath == "" {
+			continue
+		}
+
+		if info, err := os.Stat(filePath); err == nil {
+			builder.WriteString(fmt.Sprintf("%s:%d:%d;", filePath, info.ModTime().UnixNano(), info.Size()))
+		}
+	}
+
+	return builder.String()
+}
+
+// Validates a tracker configuration; if field names are provided, only those fields are validated.
+func ValidateTracker(tracker *Tracker, fields ...string) error {
+	validate := newValidator()
+	if len(fields) > 0 {
+		return validate.StructPartial(tracker, fields...)
+	}
+
+	if err := validate.Struct(tracker); err != nil {
+		return err
+	}
+
+	return ValidateCriteriaValueType(tracker.GetValueType(), tracker.NotifyCriteria)
+}
+
+// Checks that the criteria can be evaluated for the tracker value type - text criteria need text values and the rest need numbers.
+// Any change criteria work with both.
+func ValidateCriteriaValueType(valueType string, criteriaList []NotifyCriteria) error {
+	for _, criteria := range criteriaList {
+		switch {
+		case criteria.GetType() == CriteriaTypeGroup:
+			if err := ValidateCriteriaValueType(valueType, criteria.Criteria); err != nil {
+				return err
+			}
+		case criteria.GetType() == CriteriaTypeAnyChange:
+			continue
+		case criteria.IsTextCriteria() != (valueType == ValueTypeText):
+			return fmt.Errorf("notification criteria of type '%s' cannot be used by trackers with the '%s' value type", criteria.GetType(), valueType)
+		}
+	}
+
+	return nil
+}
+
+func ValidateNotifyCriteria(criteria *NotifyCriteria) error {
+	return newValidator().Struct(criteria)
+}
+
+// Creates a validator with the custom validation tags used in the configuration:
+//   - interval - a positive duration in the format supported by the tracker run interval, e.g. "10m", "1h", "2d"
+//   - cron - a standard 5 field cron expression or a descriptor, e.g. "15 9 * * 1-5", "@daily"
+//   - clock - a time of the day, e.g. "08:30"
+//   - duration - a positive Go duration, e.g. "10s", "1m30s"
+//   - proxy - a proxy URL with the http, https, socks5 or socks5h scheme
+//
+// and the notification criteria type specific validation.
+func newValidator() *validator.Validate {
+	validate := validator.New()
+	_ = validate.RegisterValidation("interval", func(fl validator.FieldLevel) bool {
+		interval, err := utilities.ParseDurationWithDays(fl.Field().String())
+		return err == nil && interval > 0
+	})
+	_ = validate.RegisterValidation("cron", func(fl validator.FieldLevel) bool {
+		_, err := cron.ParseStandard(fl.Field().String())
+		return err == nil
+	})
+	_ = validate.RegisterValidation("clock", func(fl validator.FieldLevel) bool {
+		_, err := time.Parse(ClockFormat, fl.Field().String())
+		return err == nil
+	})
+	_ = validate.RegisterValidation("proxy", func(fl validator.FieldLevel) bool {
+		return isProxyURL(fl.Field().String())
+	})
+	_ = validate.RegisterValidation("duration", func(fl validator.FieldLevel) bool {
+		duration, err := time.ParseDuration(fl.Field().String())
+		return err == nil && duration > 0
+	})
+	validate.RegisterStructValidation(validateNotifyCriteriaFields, NotifyCriteria{})
+
+	return validate
+}
+
+// Validates and adds a new tracker of the given type to the configuration; tracker codes must be unique across both types.
+func (c *Configuration) AddTracker(trackerType string, tracker *Tracker) error {
+	if err := ValidateTracker(tracker); err != nil {
+		return err
+	}
+
+	if c.GetTrackerData(tracker.Code) != nil {
+		return fmt.Errorf("tracker with code '%s' already exists", tracker.Code)
+	}
+
+	c.trackersMu.Lock()
+	defer c.trackersMu.Unlock()
+
+	switch trackerType {
+	case TrackerTypeAPI:
+		c.APITrackers = append(c.APITrackers, tracker)
+	case TrackerTypeScraper:
+		c.ScraperTrackers = append(c.ScraperTrackers, tracker)
+	default:
+		return fmt.Errorf("unsupported tracker type: %s", trackerType)
+	}
+
+	return nil
+}
+
+// Removes a tracker from the configuration; returns false if no such tracker exists.
+func (c *Configuration) RemoveTracker(code string) bool {
+	c.trackersMu.Lock()
+	defer c.trackersMu.Unlock()
+
+	var removed bool
+	c.APITrackers, removed = removeTracker(c.APITrackers, code)
+	if removed {
+		return true
+	}
+
+	c.ScraperTrackers, removed = removeTracker(c.ScraperTrackers, code)
+
+	return removed
+}
+
+func removeTracker(trackers []*Tracker, code string) ([]*Tracker, bool) {
+	for i, tracker := range trackers {
+		if tracker.Code == code {
+			return append(trackers[:i:i], trackers[i+1:]...), true
+		}
+	}
+
+	return trackers, false
+}
+
+// Returns a snapshot of the configured API trackers that is safe to iterate while trackers are being added or removed.
+func (c *Configuration) GetAPITrackers() []*Tracker {
+	c.trackersMu.RLock()
+	defer c.trackersMu.RUnlock()
+
+	return append([]*Tracker(nil), c.APITrackers...)
+}
+
+// Returns a snapshot of the configured scraper trackers that is safe to iterate while trackers are being added or removed.
+func (c *Configuration) GetScraperTrackers() []*Tracker {
+	c.trackersMu.RLock()
+	defer c.trackersMu.RUnlock()
+
+	return append([]*Tracker(nil), c.ScraperTrackers...)
 }
 
 func (c *Configuration) GetAPITrackerData(code string) *Tracker {
+	c.trackersMu.RLock()
+	defer c.trackersMu.RUnlock()
+
 	for _, tracker := range c.APITrackers {
 		if tracker.Code == code {
 			return tracker
@@ -125,6 +731,9 @@ func (c *Configuration) GetAPITrackerData(code string) *Tracker {
 }
 
 func (c *Configuration) GetScraperTrackerData(code string) *Tracker {
+	c.trackersMu.RLock()
+	defer c.trackersMu.RUnlock()
+
 	for _, tracker := range c.ScraperTrackers {
 		if tracker.Code == code {
 			return tracker
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const domainSettingsFileVar = "DOMAIN_SETTINGS_FILE"
+
+// Politeness settings of the scraping requests to a domain and its subdomains; unset fields fall back to the defaults set via the environment.
+type DomainSettings struct {
+	Domain      string `json:"domain" validate:"required,hostname"`
+	MinDelay    string `json:"minDelay,omitempty" validate:"omitempty,duration"` // Minimum time between the requests to the domain, e.g. "5s"
+	MaxParallel *int   `json:"maxParallel,omitempty" validate:"omitempty,min=0"` // Requests to the domain at the same time; 0 for no limit
+	ObeyRobots  *bool  `json:"obeyRobots,omitempty"`                             // Whether the pages disallowed by the robots.txt are not scraped
+}
+
+// Returns the minimum delay between the requests; the default one if not set.
+func (d *DomainSettings) GetMinDelay(defaultValue time.Duration) time.Duration {
+	if d.MinDelay == "" {
+		return defaultValue
+	}
+
+	minDelay, _ := time.ParseDuration(d.MinDelay) // Validated when the configuration is loaded
+
+	return minDelay
+}
+
+func (d *DomainSettings) GetMaxParallel(defaultValue int) int {
+	if d.MaxParallel == nil {
+		return defaultValue
+	}
+
+	return *d.MaxParallel
+}
+
+func (d *DomainSettings) GetObeyRobots(defaultValue bool) bool {
+	if d.ObeyRobots == nil {
+		return defaultValue
+	}
+
+	return *d.ObeyRobots
+}
+
+func loadDomainSettings() ([]*DomainSettings, error) {
+	filePath := os.Getenv(domainSettingsFileVar)
+	if filePath == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read domain settings file: %w", err)
+	}
+
+	var settings []*DomainSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON from file: %w", err)
+	}
+
+	domains := make(map[string]bool)
+	for _, domainSettings := range settings {
+		domainSettings.Domain = strings.ToLower(domainSettings.Domain)
+		if domains[domainSettings.Domain] {
+			return nil, fmt.Errorf("duplicate domain '%s'", domainSettings.Domain)
+		}
+
+		domains[domainSettings.Domain] = true
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// Authentication types of the tracker requests.
+const (
+	AuthTypeBasic  = "basic"
+	AuthTypeBearer = "bearer"
+)
+
+// References to environment variables in the header values, e.g. "${SHOP_API_KEY}".
+var envReferenceRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// How a tracker requests its data; trackers without a request spec send a plain GET request to the data URL.
+type RequestSpec struct {
+	Method   string            `json:"method,omitempty" validate:"omitempty,oneof=GET POST PUT PATCH"` // GET by default
+	Headers  map[string]string `json:"headers,omitempty"`                                              // Values may reference environment variables as ${NAME}
+	Query    map[string]string `json:"query,omitempty"`                                                // Added to the query parameters of the data URL
+	JSONBody json.RawMessage   `json:"jsonBody,omitempty"`
+	FormBody map[string]string `json:"formBody,omitempty" validate:"excluded_with=JSONBody"`
+	Auth     *RequestAuth      `json:"auth,omitempty"`
+	Timeout  string            `json:"timeout,omitempty" validate:"omitempty,duration"` // e.g. "10s"; the client default if empty
+}
+
+// Credentials of a tracker request; the secrets are read from the environment variables so that they stay out of the tracker files.
+type RequestAuth struct {
+	Type        string `json:"type" validate:"required,oneof=basic bearer"`
+	UsernameEnv string `json:"usernameEnv,omitempty" validate:"required_if=Type basic"`
+	PasswordEnv string `json:"passwordEnv,omitempty" validate:"required_if=Type basic"`
+	TokenEnv    string `json:"tokenEnv,omitempty" validate:"required_if=Type bearer"`
+}
+
+// How the proxy of a tracker request is picked.
+const (
+	ProxyRotationRoundRobin = "round-robin" // Every request goes through the next proxy
+	ProxyRotationSticky     = "sticky"      // All requests to a host go through the same proxy until it fails
+)
+
+// Proxy URL schemes supported for the tracker requests.
+var proxySchemes = []string{"http", "https", "socks5", "socks5h"}
+
+// Proxies of a tracker; they replace the global proxies set via PROXY_URLS.
+type ProxySettings struct {
+	URLs     []string `json:"urls,omitempty" validate:"dive,proxy"`
+	Rotation string   `json:"rotation,omitempty" validate:"omitempty,oneof=round-robin sticky"` // round-robin by default
+	Disabled bool     `json:"disabled,omitempty"`                                               // Sends the requests directly even if global proxies are set
+}
+
+// Whether the value is a proxy URL with a supported scheme, e.g. "socks5://10.0.0.1:1080".
+func isProxyURL(value string) bool {
+	parsedURL, err := url.Parse(value)
+
+	return err == nil && parsedURL.Host != "" && slices.Contains(proxySchemes, parsedURL.Scheme)
+}
+
+func (r *RequestSpec) GetMethod() string {
+	if r == nil || r.Method == "" {
+		return http.MethodGet
+	}
+
+	return r.Method
+}
+
+// Returns the request timeout; zero if not set.
+func (r *RequestSpec) GetTimeout() time.Duration {
+	if r == nil || r.Timeout == "" {
+		return 0
+	}
+
+	timeout, _ := time.ParseDuration(r.Timeout) // Validated when the configuration is loaded
+
+	return timeout
+}
+
+// Returns the data URL with the query parameters of the request spec added.
+func (r *RequestSpec) BuildURL(dataURL string) (string, error) {
+	if r == nil || len(r.Query) == 0 {
+		return dataURL, nil
+	}
+
+	parsedURL, err := url.Parse(dataURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := parsedURL.Query()
+	for name, value := range r.Query {
+		query.Set(name, value)
+	}
+	parsedURL.RawQuery = query.Encode()
+
+	return parsedURL.String(), nil
+}
+
+// Returns the request body and its content type; nil if the request has no body.
+func (r *RequestSpec) BuildBody() ([]byte, string) {
+	switch {
+	case r == nil:
+		return nil, ""
+	case len(r.JSONBody) > 0:
+		return r.JSONBody, "application/json"
+	case len(r.FormBody) > 0:
+		form := url.Values{}
+		for name, value := range r.FormBody {
+			form.Set(name, value)
+		}
+
+		return []byte(form.Encode()), "application/x-www-form-urlencoded"
+	default:
+		return nil, ""
+	}
+}
+
+// Returns the request headers with the environment variable references and the authentication resolved.
+func (r *RequestSpec) BuildHeaders() (map[string]string, error) {
+	headers := make(map[string]string)
+	if r == nil {
+		return headers, nil
+	}
+
+	for name, value := range r.Headers {
+		resolved, err := resolveEnvReferences(value)
+		if err != nil {
+			return nil, fmt.Errorf("header '%s': %w", name, err)
+		}
+
+		headers[name] = resolved
+	}
+
+	if r.Auth != nil {
+		authorization, err := r.Auth.header()
+		if err != nil {
+			return nil, err
+		}
+
+		headers["Authorization"] = authorization
+	}
+
+	return headers, nil
+}
+
+// Returns the value of the Authorization header.
+func (a *RequestAuth) header() (string, error) {
+	switch a.Type {
+	case AuthTypeBasic:
+		username, err := getSecret(a.UsernameEnv)
+		if err != nil {
+			return "", err
+		}
+
+		password, err := getSecret(a.PasswordEnv)
+		if err != nil {
+			return "", err
+		}
+
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)), nil
+	case AuthTypeBearer:
+		token, err := getSecret(a.TokenEnv)
+		if err != nil {
+			return "", err
+		}
+
+		return "Bearer " + token, nil
+	default:
+		return "", fmt.Errorf("unsupported request auth type: %s", a.Type)
+	}
+}
+
+func resolveEnvReferences(value string) (string, error) {
+	var resolveErr error
+	resolved := envReferenceRegex.ReplaceAllStringFunc(value, func(reference string) string {
+		secret, err := getSecret(envReferenceRegex.FindStringSubmatch(reference)[1])
+		if err != nil && resolveErr == nil {
+			resolveErr = err
+		}
+
+		return secret
+	})
+
+	return resolved, resolveErr
+}
+
+func getSecret(envVar string) (string, error) {
+	value := os.Getenv(envVar)
+	if value == "" {
+		return "", fmt.Errorf("environment variable %s is not set", envVar)
+	}
+
+	return value, nil
+}
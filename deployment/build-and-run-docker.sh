@@ -29,6 +29,6 @@ docker build -t $container_name .  # The dot refers to the project root
 
 # Run the container
 echo "Starting a new container: $container_name"
-docker run --name $container_name --env-file .env -p 7080:8080 -d $container_name
+docker run --name $container_name --env-file .env -p 7080:8080 -v "$(pwd)/data:/root/data" -d $container_name
 
 echo "Deployment completed."
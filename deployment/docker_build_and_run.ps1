@@ -28,6 +28,6 @@ docker build -t $containerName .
 
 # Run the container with the correct path to the .env file
 Write-Host "Starting a new container: $containerName"
-docker run --name $containerName --env-file .env -p 7080:8080 $containerName 
+docker run --name $containerName --env-file .env -p 7080:8080 -v ${PWD}/data:/root/data $containerName 
 
 Read-Host -Prompt "Press Enter to exit"
@@ -18,7 +18,7 @@ if ($runningContainer) {
     docker start $containerName
 } else {
     Write-Host "No existing container found. Creating and starting a new container: $containerName"
-    docker run --name $containerName --env-file .env -p 7080:8080 $containerName
+    docker run --name $containerName --env-file .env -p 7080:8080 -v ${PWD}/data:/root/data $containerName
 }
 
 Read-Host -Prompt "Press Enter to exit"
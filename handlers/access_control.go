@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrAccessDenied  = errors.New("access denied")
+	ErrAdminRequired = errors.New("admin rights required")
+)
+
+const (
+	accessDeniedMessage  = "Sorry, you are not allowed to use this bot. Please ask the bot owner for access"
+	adminRequiredMessage = "Sorry, only the bot admins can use this command"
+)
+
+/*
+Checks whether the user may use the bot in the chat. Access is granted to the allowed users, to everyone in the
+allowed chats (e.g. a group chat) and to the admins. If no users, chats or admins are configured, the bot is open to everyone.
+*/
+func (ch *CommandHandler) IsAllowed(userID int64, chatID int64) bool {
+	if len(ch.config.AllowedUserIDs) == 0 && len(ch.config.AllowedChatIDs) == 0 && len(ch.config.AdminUserIDs) == 0 {
+		return true
+	}
+
+	return slices.Contains(ch.config.AllowedUserIDs, userID) ||
+		slices.Contains(ch.config.AllowedChatIDs, chatID) ||
+		slices.Contains(ch.config.AdminUserIDs, userID)
+}
+
+// Checks whether the user may use the admin commands; nobody can use them if no admins are configured.
+func (ch *CommandHandler) isAdmin(userID int64) bool {
+	return slices.Contains(ch.config.AdminUserIDs, userID)
+}
+
+// Checks whether the user may run the command in the chat; returns ErrAccessDenied or ErrAdminRequired if not.
+func (ch *CommandHandler) CheckAccess(userID int64, chatID int64, commandString string) error {
+	if !ch.IsAllowed(userID, chatID) {
+		return ErrAccessDenied
+	}
+
+	commandName, trackerCode := parseCommandName(commandString)
+	if c, exists := ch.commandMap[commandName]; exists && c.requiresAdmin(trackerCode) && !ch.isAdmin(userID) {
+		return ErrAdminRequired
+	}
+
+	return nil
+}
+
+// Polite explanation of why the command has been rejected.
+func AccessErrorMessage(err error) string {
+	if errors.Is(err, ErrAdminRequired) {
+		return adminRequiredMessage
+	}
+
+	return accessDeniedMessage
+}
+
+func (c *Command) requiresAdmin(trackerCode string) bool {
+	if trackerCode == "" {
+		return c.AdminOnly || c.AdminOnlyGeneral
+	}
+
+	return c.AdminOnly
+}
+
+// Extracts the command name and the tracker code from a command string, e.g. "/stop@PriceTrackerBot bonds".
+func parseCommandName(commandString string) (string, string) {
+	commandParts := strings.Split(commandString, " ")
+	command := strings.ReplaceAll(commandParts[0], "/", "")
+	// Commands sent in group chats may be addressed to the bot, e.g. /status@PriceTrackerBot
+	command, _, _ = strings.Cut(command, "@")
+
+	if len(commandParts) > 1 {
+		return command, commandParts[1]
+	}
+
+	return command, ""
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"pricetrackerbot/config"
+)
+
+func TestCheckAccess(t *testing.T) {
+	const (
+		admin       = 1
+		allowedUser = 2
+		otherUser   = 3
+		groupChat   = -100
+		otherChat   = -200
+	)
+
+	commandMap := map[string]*Command{
+		"status": {},
+		"stop":   {AdminOnlyGeneral: true},
+		"reload": {AdminOnly: true},
+	}
+
+	tests := []struct {
+		name          string
+		configuration *config.Configuration
+		userID        int64
+		chatID        int64
+		command       string
+		want          error
+	}{
+		{
+			name:    "open bot allows the regular commands",
+			userID:  otherUser,
+			chatID:  otherUser,
+			command: "/status bonds",
+		},
+		{
+			name:    "open bot without admins denies the admin commands",
+			userID:  otherUser,
+			chatID:  otherUser,
+			command: "/reload",
+			want:    ErrAdminRequired,
+		},
+		{
+			name:          "allowed user",
+			configuration: &config.Configuration{AllowedUserIDs: []int64{allowedUser}},
+			userID:        allowedUser,
+			chatID:        otherChat,
+			command:       "/status",
+		},
+		{
+			name:          "user not allowed",
+			configuration: &config.Configuration{AllowedUserIDs: []int64{allowedUser}},
+			userID:        otherUser,
+			chatID:        otherUser,
+			command:       "/status",
+			want:          ErrAccessDenied,
+		},
+		{
+			name:          "anyone in an allowed chat",
+			configuration: &config.Configuration{AllowedChatIDs: []int64{groupChat}},
+			userID:        otherUser,
+			chatID:        groupChat,
+			command:       "/status@PriceTrackerBot",
+		},
+		{
+			name:          "admin is always allowed",
+			configuration: &config.Configuration{AllowedUserIDs: []int64{allowedUser}, AdminUserIDs: []int64{admin}},
+			userID:        admin,
+			chatID:        admin,
+			command:       "/reload",
+		},
+		{
+			name:          "allowed user cannot use the admin commands",
+			configuration: &config.Configuration{AllowedUserIDs: []int64{allowedUser}, AdminUserIDs: []int64{admin}},
+			userID:        allowedUser,
+			chatID:        allowedUser,
+			command:       "/reload@PriceTrackerBot",
+			want:          ErrAdminRequired,
+		},
+		{
+			name:          "allowed user can stop a single tracker",
+			configuration: &config.Configuration{AllowedUserIDs: []int64{allowedUser}, AdminUserIDs: []int64{admin}},
+			userID:        allowedUser,
+			chatID:        allowedUser,
+			command:       "/stop bonds",
+		},
+		{
+			name:          "allowed user cannot stop all trackers",
+			configuration: &config.Configuration{AllowedUserIDs: []int64{allowedUser}, AdminUserIDs: []int64{admin}},
+			userID:        allowedUser,
+			chatID:        allowedUser,
+			command:       "/stop",
+			want:          ErrAdminRequired,
+		},
+		{
+			name:          "unknown command is left to the command handling",
+			configuration: &config.Configuration{AllowedUserIDs: []int64{allowedUser}},
+			userID:        allowedUser,
+			chatID:        allowedUser,
+			command:       "/unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configuration := tt.configuration
+			if configuration == nil {
+				configuration = &config.Configuration{}
+			}
+
+			ch := &CommandHandler{config: configuration, commandMap: commandMap}
+
+			if err := ch.CheckAccess(tt.userID, tt.chatID, tt.command); !errors.Is(err, tt.want) {
+				t.Errorf("CheckAccess() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCommandName(t *testing.T) {
+	tests := []struct {
+		command  string
+		wantName string
+		wantCode string
+	}{
+		{command: "/status", wantName: "status"},
+		{command: "/status bonds", wantName: "status", wantCode: "bonds"},
+		{command: "/status@PriceTrackerBot", wantName: "status"},
+		{command: "/status@PriceTrackerBot bonds", wantName: "status", wantCode: "bonds"},
+		{command: "/criteria bonds add >= 3.5", wantName: "criteria", wantCode: "bonds"},
+		{command: "status", wantName: "status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			name, code := parseCommandName(tt.command)
+			if name != tt.wantName || code != tt.wantCode {
+				t.Errorf("parseCommandName(%q) = %q, %q, want %q, %q", tt.command, name, code, tt.wantName, tt.wantCode)
+			}
+		})
+	}
+}
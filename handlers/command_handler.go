@@ -3,14 +3,20 @@ package handlers
 import (
 	"errors"
 	"fmt"
+	"html"
 	"log"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"pricetrackerbot/clients"
 	"pricetrackerbot/config"
 	"pricetrackerbot/helpers"
+	"pricetrackerbot/scheduling"
+	"pricetrackerbot/services"
+	"pricetrackerbot/storage"
 	"pricetrackerbot/utilities"
 )
 
@@ -26,65 +32,184 @@ type Command struct {
 	DescriptionGeneral string
 	DescriptionTracker string
 	Hidden             bool // Whether the command shows up in the help menu
+	AdminOnly          bool // Whether only the admins can use the command
+	AdminOnlyGeneral   bool // Whether only the admins can use the general form of the command, i.e. without a tracker code
 	Params             []string
 	Handler            CommandFunc
 }
 
 type CommandHandler struct {
-	AwaitingUserInput    bool
-	CustomKeyboardActive bool
-	config               *config.Configuration
-	runningTrackers      []*Tracker
-	commandMap           map[string]*Command
-	bot                  *tgbotapi.BotAPI
-	mu                   sync.Mutex
-	Navigation           map[int64]*NavigationState
+	config          *config.Configuration
+	runningTrackers []*Tracker // Trackers of all the chats; every chat runs its own instances
+	fetchCache      *clients.FetchCache
+	scheduler       *scheduling.Scheduler // Runs the trackers of all the chats
+	commandMap      map[string]*Command
+	bot             *tgbotapi.BotAPI
+	storage         storage.Storage
+	mu              sync.Mutex
+	reloadMu        sync.Mutex
+	navigationMu    sync.Mutex
+	Navigation      map[int64]*NavigationState // Conversation state of every chat
 }
 
 type CommandFunc func(code string, chatID int64, commandParam *string) error
 
-func NewCommandHandler(bot *tgbotapi.BotAPI) *CommandHandler {
+func NewCommandHandler(bot *tgbotapi.BotAPI, store storage.Storage) *CommandHandler {
 	ch := &CommandHandler{
-		config:               config.GetConfig(),
-		bot:                  bot,
-		AwaitingUserInput:    false,
-		CustomKeyboardActive: false,
-		Navigation:           make(map[int64]*NavigationState),
-	}
+		config:     config.GetConfig(),
+		bot:        bot,
+		storage:    store,
+		Navigation: make(map[int64]*NavigationState),
+	}
+	ch.fetchCache = clients.NewFetchCache(ch.config.FetchCacheTTL, newHistoryRecorder(store))
+	services.SetDefaultClient(newHTTPClient(ch.config))
+	ch.scheduler = scheduling.NewScheduler(
+		ch.config.SchedulerWorkers,
+		scheduling.HostLimits{Concurrency: ch.config.HostConcurrency, MinSpacing: ch.config.HostMinSpacing},
+		ch.config.SchedulerMaxJitter,
+	)
+	ch.scheduler.Start()
 
 	ch.commandMap = map[string]*Command{
-		"start":    {Type: generalType, DescriptionGeneral: "Bot start command", Handler: ch.handleHelp, Hidden: true, Params: []string{"tracker_code"}},
-		"run":      {Type: bothType, DescriptionTracker: "Run a tracker", DescriptionGeneral: "Run all available trackers", Handler: ch.handleStart, Hidden: false, Params: []string{"tracker_code"}},
-		"stop":     {Type: bothType, DescriptionTracker: "Stop a tracker", DescriptionGeneral: "Stop all running trackers", Handler: ch.handleStop, Hidden: false, Params: []string{"tracker_code"}},
-		"interval": {Type: trackerType, DescriptionTracker: "Change the tracker run interval", Handler: ch.handleSetInterval, Hidden: false, Params: []string{"tracker_code", "interval*"}},
-		"status":   {Type: bothType, DescriptionTracker: "View a particular tracker status", DescriptionGeneral: "View status of all available trackers", Handler: ch.handleStatus, Hidden: false, Params: []string{"tracker_code"}},
-		"help":     {Type: generalType, DescriptionGeneral: "View all available commands", Handler: ch.handleHelp, Hidden: false},
-	}
+		"start":       {Type: generalType, DescriptionGeneral: "Bot start command", Handler: ch.handleHelp, Hidden: true, Params: []string{"tracker_code"}},
+		"run":         {Type: bothType, DescriptionTracker: "Run a tracker", DescriptionGeneral: "Run all available trackers", Handler: ch.handleStart, Hidden: false, Params: []string{"tracker_code"}},
+		"stop":        {Type: bothType, DescriptionTracker: "Stop a tracker", DescriptionGeneral: "Stop all running trackers", Handler: ch.handleStop, Hidden: false, AdminOnlyGeneral: true, Params: []string{"tracker_code"}},
+		"interval":    {Type: trackerType, DescriptionTracker: "Change the tracker run interval", Handler: ch.handleSetInterval, Hidden: false, Params: []string{"tracker_code", "interval*"}},
+		"status":      {Type: bothType, DescriptionTracker: "View a particular tracker status", DescriptionGeneral: "View status of all available trackers", Handler: ch.handleStatus, Hidden: false, Params: []string{"tracker_code"}},
+		"help":        {Type: generalType, DescriptionGeneral: "View all available commands", Handler: ch.handleHelp, Hidden: false},
+		"add":         {Type: generalType, DescriptionGeneral: "Add a new tracker", Handler: ch.handleAdd, Hidden: false, AdminOnly: true},
+		"remove":      {Type: trackerType, DescriptionTracker: "Remove a tracker that has been added via the bot", Handler: ch.handleRemove, Hidden: false, AdminOnly: true, Params: []string{"tracker_code"}},
+		"criteria":    {Type: trackerType, DescriptionTracker: "View and edit the tracker notification criteria", Handler: ch.handleCriteria, Hidden: false, AdminOnly: true, Params: []string{"tracker_code"}},
+		"reload":      {Type: generalType, DescriptionGeneral: "Reload the tracker configuration files", Handler: ch.handleReload, Hidden: false, AdminOnly: true},
+		"subscribe":   {Type: trackerType, DescriptionTracker: "Receive the notifications of a tracker running in another chat", Handler: ch.handleSubscribe, Hidden: false, Params: []string{"tracker_code"}},
+		"resume":      {Type: trackerType, DescriptionTracker: "Resume a tracker paused after repeated failures", Handler: ch.handleResume, Hidden: false, Params: []string{"tracker_code"}},
+		"errors":      {Type: trackerType, DescriptionTracker: "View the recent execution errors of a tracker and clear them", Handler: ch.handleErrors, Hidden: false, Params: []string{"tracker_code"}},
+		"cancel":      {Type: generalType, DescriptionGeneral: "Cancel the current operation, e.g. adding a tracker", Handler: ch.handleCancel, Hidden: false},
+		"unsubscribe": {Type: trackerType, DescriptionTracker: "Stop receiving the notifications of a subscribed tracker", Handler: ch.handleUnsubscribe, Hidden: false, Params: []string{"tracker_code"}},
+	}
+
+	ch.loadStoredTrackers()
+	ch.restoreRunningTrackers()
+
+	go ch.watchTrackerFiles()
 
 	return ch
 }
 
-func (ch *CommandHandler) HandleCommand(chatID int64, commandString string, callbackMessageID *int, isReturn bool) error {
+// Creates the HTTP client shared by the trackers from the configuration.
+func newHTTPClient(config *config.Configuration) *services.HTTPClient {
+	var proxies *services.ProxyPool
+	// Without global proxies the proxy environment variables, e.g. HTTPS_PROXY, apply
+	if len(config.ProxyURLs) > 0 {
+		var err error
+		if proxies, err = services.NewProxyPool(config.ProxyURLs, config.ProxyRotation); err != nil {
+			log.Fatalf("[CommandHandler] Invalid proxy configuration: %v", err)
+		}
+	}
+
+	return services.NewHTTPClient(services.ClientOptions{
+		Timeout:       config.HTTPTimeout,
+		MaxRedirects:  config.HTTPMaxRedirects,
+		MaxBodySize:   int64(config.HTTPMaxBodySize),
+		RetryAttempts: config.HTTPRetryAttempts,
+		RetryMaxWait:  config.HTTPRetryMaxWait,
+		UserAgent:     config.UserAgent,
+		Proxies:       proxies,
+		Politeness:    newPolitenessOptions(config),
+	})
+}
+
+// Builds the politeness options of the scraping requests; the domain settings fall back to the defaults field by field.
+func newPolitenessOptions(config *config.Configuration) services.PolitenessOptions {
+	defaultPolicy := services.DomainPolicy{
+		MinDelay:    config.ScraperMinDelay,
+		MaxParallel: config.ScraperMaxParallel,
+		ObeyRobots:  config.ObeyRobotsTxt,
+	}
+
+	domains := make(map[string]services.DomainPolicy)
+	for _, settings := range config.DomainSettings {
+		domains[settings.Domain] = services.DomainPolicy{
+			MinDelay:    settings.GetMinDelay(defaultPolicy.MinDelay),
+			MaxParallel: settings.GetMaxParallel(defaultPolicy.MaxParallel),
+			ObeyRobots:  settings.GetObeyRobots(defaultPolicy.ObeyRobots),
+		}
+	}
+
+	return services.PolitenessOptions{Default: defaultPolicy, Domains: domains, RobotsCacheTTL: config.RobotsCacheTTL}
+}
+
+// Restarts the trackers that were running before the application was restarted and lets their owners know about it.
+func (ch *CommandHandler) restoreRunningTrackers() {
+	states, err := ch.storage.GetTrackerStates()
+	if err != nil {
+		log.Printf("[CommandHandler] Error loading persisted tracker states: %s", err.Error())
+		return
+	}
+
+	restoredTrackers := make(map[int64][]string)
+
+	for _, state := range states {
+		tracker, err := ch.createTracker(state.TrackerCode, state.Interval, state.ChatID)
+		if err != nil {
+			log.Printf("[CommandHandler] Failed to restore tracker '%s': %s", state.TrackerCode, err.Error())
+			ch.deleteTrackerState(state.ChatID, state.TrackerCode)
+
+			continue
+		}
+
+		tracker.RestoreState(state)
+		ch.AddRunningTracker(tracker)
+		tracker.Start()
+		restoredCode := state.TrackerCode
+		if state.Paused {
+			restoredCode += " (paused)"
+		}
+
+		restoredTrackers[state.ChatID] = append(restoredTrackers[state.ChatID], restoredCode)
+		log.Printf("[CommandHandler] Restored tracker: %s", state.TrackerCode)
+	}
+
+	for chatID, codes := range restoredTrackers {
+		helpers.SendMessageHTML(ch.bot, chatID, "The bot has been restarted, the following trackers have been resumed: <b>"+strings.Join(codes, ", ")+"</b>", nil)
+	}
+}
+
+func (ch *CommandHandler) HandleCommand(chatID int64, userID int64, commandString string, callbackMessageID *int, isReturn bool) error {
+	// Also covers the user input and return flows, e.g. another group member replying to a prompt of an admin command
+	if err := ch.CheckAccess(userID, chatID, commandString); err != nil {
+		log.Printf("[CommandHandler] User %d is not allowed to run command '%s' in chat %d: %s", userID, commandString, chatID, err.Error())
+		helpers.SendMessageHTML(ch.bot, chatID, AccessErrorMessage(err), nil)
+
+		return err
+	}
+
 	// Message ID is only available when handling commands as a result of a button callback
 	ch.GetUserNavigationState(chatID).CallbackMessageID = callbackMessageID
 
 	// Most commands have one parameter - tracker code - but it is possible that some may have more
 	commandParts := strings.Split(commandString, " ")
-	command := strings.ReplaceAll(commandParts[0], "/", "")
+	command, _ := parseCommandName(commandString)
 	var trackerCode, commandParam *string
 
 	if len(commandParts) > 1 {
 		trackerCode = &commandParts[1]
 	}
 
+	// The rest of the command is treated as a single parameter as user input may contain spaces
 	if len(commandParts) > 2 { //nolint:mnd
-		commandParam = &commandParts[2]
+		param := strings.Join(commandParts[2:], " ")
+		commandParam = &param
 	}
 
 	log.Printf("[CommandHandler] Handling command: %s", commandString)
 
 	if c, exists := ch.commandMap[command]; exists {
-		if !isReturn {
+		// The cancel command ends the conversation itself and has nothing to return to
+		if !isReturn && command != cancelCommand {
+			// A new command abandons any unfinished multi-step conversation
+			ch.endConversation(chatID)
+
 			ch.GetUserNavigationState(chatID).Push(
 				&Command{
 					Command: command,
@@ -106,7 +231,7 @@ func (ch *CommandHandler) HandleCommand(chatID int64, commandString string, call
 	return nil
 }
 
-func (ch *CommandHandler) HandleReturn(chatID int64, callbackMessageID *int) error {
+func (ch *CommandHandler) HandleReturn(chatID int64, userID int64, callbackMessageID *int) error {
 	ch.GetUserNavigationState(chatID).Pop()
 	gotoCommand := ch.GetUserNavigationState(chatID).Peek()
 
@@ -115,19 +240,39 @@ func (ch *CommandHandler) HandleReturn(chatID int64, callbackMessageID *int) err
 		commandString += " " + strings.Join(gotoCommand.Params, " ")
 	}
 
-	if err := ch.HandleCommand(chatID, commandString, callbackMessageID, true); err != nil {
+	if err := ch.HandleCommand(chatID, userID, commandString, callbackMessageID, true); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (ch *CommandHandler) HandleUserInput(chatID int64, userInput string, callbackMessageID *int) error {
-	ch.AwaitingUserInput = false
+/*
+Passes the user input to the command that has prompted for it. Input of the expected type only is accepted - otherwise
+the user is asked to try again. Input sent after the prompt has expired is ignored and the conversation abandoned.
+*/
+func (ch *CommandHandler) HandleUserInput(chatID int64, userID int64, userInput string, callbackMessageID *int) error {
+	navigationState := ch.GetUserNavigationState(chatID)
+	prompt := navigationState.takePendingInput()
+	if prompt == nil {
+		return nil
+	}
+
 	// Hide keyboard after user input
-	if ch.CustomKeyboardActive {
+	if prompt.keyboard != nil {
 		helpers.SendMessageRemoveKeyboard(ch.bot, chatID)
-		ch.CustomKeyboardActive = false
+	}
+
+	if prompt.isExpired() {
+		navigationState.trackerDraft = nil
+		helpers.SendMessageHTML(ch.bot, chatID, "Sorry, I have stopped waiting for your answer, please start over", nil)
+
+		return errors.New("user input prompt expired")
+	}
+
+	if explanation, valid := validateUserInput(prompt.inputType, userInput); !valid {
+		ch.promptInput(chatID, prompt.inputType, explanation+". Try again or send /"+cancelCommand+" to cancel", prompt.keyboard)
+		return errors.New("invalid user input")
 	}
 
 	gotoCommand := ch.GetUserNavigationState(chatID).Peek()
@@ -138,15 +283,32 @@ func (ch *CommandHandler) HandleUserInput(chatID int64, userInput string, callba
 
 	commandString = strings.TrimSpace(commandString) + " " + userInput
 
-	if err := ch.HandleCommand(chatID, commandString, callbackMessageID, true); err != nil {
+	if err := ch.HandleCommand(chatID, userID, commandString, callbackMessageID, true); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// Creates a tracker instance of the chat with the notification criteria the chat has set for it.
+func (ch *CommandHandler) createTracker(trackerCode string, runInterval time.Duration, chatID int64) (*Tracker, error) {
+	tracker, err := CreateTracker(ch.bot, trackerCode, runInterval, ch.config, chatID, ch.storage, ch.fetchCache, ch.scheduler)
+	if err != nil {
+		return nil, err
+	}
+
+	override, err := ch.storage.GetCriteriaOverride(chatID, trackerCode)
+	if err != nil {
+		log.Printf("[CommandHandler] Error loading notification criteria override of tracker '%s': %s", trackerCode, err.Error())
+	} else if override != nil {
+		tracker.SetNotifyCriteria(override.NotifyCriteria)
+	}
+
+	return tracker, nil
+}
+
 func (ch *CommandHandler) startTracker(trackerCode string, chatID int64, errors map[string]error) {
-	if newTracker, err := CreateTracker(ch.bot, trackerCode, 0, ch.config, chatID); err != nil {
+	if newTracker, err := ch.createTracker(trackerCode, 0, chatID); err != nil {
 		errors[trackerCode] = err
 	} else {
 		ch.AddRunningTracker(newTracker)
@@ -158,14 +320,14 @@ func (ch *CommandHandler) startTracker(trackerCode string, chatID int64, errors
 func (ch *CommandHandler) startAllTrackers(chatID int64) {
 	errors := make(map[string]error)
 
-	for _, tracker := range ch.config.APITrackers {
-		if tr := ch.GetActiveTracker(tracker.Code); tr == nil {
+	for _, tracker := range ch.config.GetAPITrackers() {
+		if tr := ch.GetActiveTracker(chatID, tracker.Code); tr == nil {
 			ch.startTracker(tracker.Code, chatID, errors)
 		}
 	}
 
-	for _, tracker := range ch.config.ScraperTrackers {
-		if tr := ch.GetActiveTracker(tracker.Code); tr == nil {
+	for _, tracker := range ch.config.GetScraperTrackers() {
+		if tr := ch.GetActiveTracker(chatID, tracker.Code); tr == nil {
 			ch.startTracker(tracker.Code, chatID, errors)
 		}
 	}
@@ -192,12 +354,12 @@ func (ch *CommandHandler) handleStart(code string, chatID int64, _ *string) erro
 	}
 
 	// Start a specific tracker
-	if tracker := ch.GetActiveTracker(code); tracker == nil {
-		newTracker, err := CreateTracker(ch.bot, code, 0, ch.config, chatID)
+	if tracker := ch.GetActiveTracker(chatID, code); tracker == nil {
+		newTracker, err := ch.createTracker(code, 0, chatID)
 		if err != nil {
 			log.Printf("[CommandHandler] Error creating a new tracker: %s", code)
 			message := "Failed to start the tracker :("
-			if err.Error() == "uncregonzied tracker code" {
+			if errors.Is(err, ErrUnknownTracker) {
 				message = "Invalid command, tracker with code '" + code + "' not found :("
 			}
 
@@ -219,18 +381,19 @@ func (ch *CommandHandler) handleStart(code string, chatID int64, _ *string) erro
 }
 
 func (ch *CommandHandler) handleStop(code string, chatID int64, _ *string) error {
-	// Stop all trackers
+	// Stop all trackers of the chat
 	if code == "" {
-		ch.StopAllTrackers()
+		ch.StopAllTrackers(chatID)
 		ch.handleCommandMessage(chatID, "All running trackers have been stopped", nil)
 
 		return nil
 	}
 
 	// Stop a specific tracker
-	if tracker := ch.GetActiveTracker(code); tracker != nil {
-		ch.RemoveRunningTracker(code)
+	if tracker := ch.GetActiveTracker(chatID, code); tracker != nil {
 		tracker.Stop()
+		ch.RemoveRunningTracker(chatID, code)
+		ch.notifySubscribersStopped(tracker)
 		ch.handleCommandMessage(chatID, "Tracker '"+code+"' has been stopped", nil)
 	} else {
 		log.Printf("[CommandHandler] Tracker '%s' is not running", code)
@@ -240,53 +403,56 @@ func (ch *CommandHandler) handleStop(code string, chatID int64, _ *string) error
 	return nil
 }
 
-func (ch *CommandHandler) StopAllTrackers() {
+// Stops all the trackers running in the chat.
+func (ch *CommandHandler) StopAllTrackers(chatID int64) {
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
 
+	remainingTrackers := make([]*Tracker, 0, len(ch.runningTrackers))
 	for _, tracker := range ch.runningTrackers {
+		if tracker.chatID != chatID {
+			remainingTrackers = append(remainingTrackers, tracker)
+			continue
+		}
+
 		tracker.Stop()
+		ch.deleteTrackerState(chatID, tracker.Code)
+		ch.notifySubscribersStopped(tracker)
 	}
-	ch.runningTrackers = nil
+	ch.runningTrackers = remainingTrackers
 }
 
 func (ch *CommandHandler) handleSetInterval(code string, chatID int64, commandParam *string) error {
-	// Means command was initiated from a menu with a button
-	if ch.GetUserNavigationState(chatID).CallbackMessageID != nil {
-		ch.CustomKeyboardActive = true
-
-		helpers.SendMessageHTMLWithKeyboard(
-			ch.bot,
-			chatID, "Send me the new interval value!\n\nThe format: <i>[number][interval type*]</i>\n\nAvailable interval types: \n'm'(minute), 'h'(hour), 'd'(day)",
-			nil,
+	// Means command was initiated from a menu with a button or the value has not been provided
+	if ch.GetUserNavigationState(chatID).CallbackMessageID != nil || commandParam == nil {
+		ch.promptInput(
+			chatID,
+			inputTypeInterval,
+			"Send me the new interval value!\n\nThe format: <i>[number][interval type*]</i>\n\nAvailable interval types: \n'm'(minute), 'h'(hour), 'd'(day)",
 			helpers.GetIntervalCustomMenu(),
 		)
-		ch.AwaitingUserInput = true
 
 		return nil
 	}
 
-	if commandParam == nil {
-		log.Printf("[CommandHandler] No interval value provided")
-		ch.handleCommandMessage(chatID, "No interval value provided", nil)
-
-		return errors.New("no interval value provided")
-	}
-
 	newInterval, err := utilities.ParseDurationWithDays(*commandParam)
 	if err != nil {
 		log.Printf("[CommandHandler] Invalid interval value: %s", err.Error())
-		ch.handleCommandMessage(chatID, "Invalid interval value. Available interval types: 'm'(minute), 'h'(hour), 'd'(day)", nil)
+		ch.handleCommandMessage(chatID, "Invalid interval value, it must be a positive number. Available interval types: 'm'(minute), 'h'(hour), 'd'(day)", nil)
 
 		return err
 	}
 
-	if tracker := ch.GetActiveTracker(code); tracker != nil {
-		tracker.Stop()
-		tracker.UpdateInterval(newInterval)
-		tracker.Start()
+	if tracker := ch.GetActiveTracker(chatID, code); tracker != nil {
+		if err := tracker.UpdateInterval(newInterval); err != nil {
+			log.Printf("[CommandHandler] Error updating tracker '%s' interval: %s", code, err.Error())
+			ch.handleCommandMessage(chatID, "Failed to update the tracker <b>"+code+"</b> run interval: "+html.EscapeString(err.Error()), nil)
+
+			return err
+		}
+
 		log.Printf("[CommandHandler] Updated tracker <b>%s</b> interval to %s", code, newInterval)
-		ch.handleCommandMessage(chatID, "Tracker <b>"+code+"</b> run interval successfully updated to "+utilities.DurationToString(newInterval), nil)
+		ch.handleCommandMessage(chatID, "Tracker <b>"+code+"</b> run interval successfully updated to "+utilities.DurationToString(newInterval)+"\nSchedule: "+tracker.DescribeSchedule(), nil)
 
 		return nil
 	}
@@ -304,13 +470,13 @@ func (ch *CommandHandler) handleStatus(code string, chatID int64, _ *string) err
 
 		var builder strings.Builder
 		builder.WriteString("<b>All available trackers</b>\n\n")
-		for _, tracker := range ch.config.APITrackers {
-			activeStatus := ch.processTrackerStatus(tracker, statusMenu)
+		for _, tracker := range ch.config.GetAPITrackers() {
+			activeStatus := ch.processTrackerStatus(tracker, chatID, statusMenu)
 			builder.WriteString(fmt.Sprintf(" - %s | %s | api\n", tracker.Code, activeStatus))
 		}
 
-		for _, tracker := range ch.config.ScraperTrackers {
-			activeStatus := ch.processTrackerStatus(tracker, statusMenu)
+		for _, tracker := range ch.config.GetScraperTrackers() {
+			activeStatus := ch.processTrackerStatus(tracker, chatID, statusMenu)
 			builder.WriteString(fmt.Sprintf(" - %s | %s | scraper\n", tracker.Code, activeStatus))
 		}
 
@@ -327,7 +493,14 @@ func (ch *CommandHandler) handleStatus(code string, chatID int64, _ *string) err
 
 	statusMenu := tgbotapi.NewInlineKeyboardMarkup()
 
-	tracker := ch.GetActiveTracker(code)
+	// Chats subscribed to a tracker of another chat can view its status but not manage it
+	tracker := ch.GetActiveTracker(chatID, code)
+	subscribed := false
+	if tracker == nil {
+		tracker = ch.getSubscribedTracker(chatID, code)
+		subscribed = tracker != nil
+	}
+
 	if tracker == nil {
 		log.Printf("[CommandHandler] Tracker '%s' is not active", code)
 		statusMenu.InlineKeyboard = append(statusMenu.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
@@ -352,14 +525,55 @@ func (ch *CommandHandler) handleStatus(code string, chatID int64, _ *string) err
 
 	var builder strings.Builder
 	builder.WriteString(fmt.Sprintf("<b>Status for tracker %s</b>\n\n", code))
-	builder.WriteString("Status: active\n")
+	switch {
+	case subscribed:
+		builder.WriteString("Status: active in another chat, this chat is subscribed\n")
+	case tracker.Status.Paused:
+		builder.WriteString(fmt.Sprintf("Status: paused after %d consecutive failed runs\n", tracker.Status.ConsecutiveFailures))
+	default:
+		builder.WriteString("Status: active\n")
+	}
+
 	builder.WriteString("Tracker started: " + tracker.Status.StartTimestamp.Format("02.01.2006 15:04") + "\n")
 	builder.WriteString("Last run: " + lastRun + "\n")
 	builder.WriteString("Total runs: " + strconv.Itoa(tracker.Status.TotalRuns) + "\n")
-	builder.WriteString("Last recorded value: " + lastRecordedValue + "\n")
-	builder.WriteString(helpers.FormatNotificationCriteriaString(tracker.trackerData.NotifyCriteria) + "\n")
+	builder.WriteString("Last recorded value: " + html.EscapeString(lastRecordedValue) + "\n")
+	builder.WriteString(helpers.FormatNotificationCriteriaString(tracker.GetNotifyCriteria()) + "\n")
 	builder.WriteString("Current run interval: " + utilities.DurationToString(tracker.Status.CurrentInterval) + "\n")
-	builder.WriteString("Execution errors count: " + strconv.Itoa(len(tracker.Status.ExecutionErrors)) + "\n")
+	builder.WriteString("Schedule: " + tracker.DescribeSchedule() + "\n")
+	builder.WriteString("Next run: " + formatNextRun(tracker.Status.NextRunTimestamp) + "\n")
+	builder.WriteString("Execution errors count: " + strconv.Itoa(tracker.Status.ExecutionErrors.Total()) + "\n")
+	if tracker.Status.ProxyFailures > 0 {
+		builder.WriteString("Proxy failures count: " + strconv.Itoa(tracker.Status.ProxyFailures) + "\n")
+	}
+	if tracker.Status.ConsecutiveFailures > 0 {
+		builder.WriteString("Consecutive failed runs: " + strconv.Itoa(tracker.Status.ConsecutiveFailures) + "\n")
+		if lastError := tracker.Status.ExecutionErrors.Last(); lastError != nil {
+			builder.WriteString("Last error: [" + lastError.Category + "] " + describeError(lastError.Error) + "\n")
+		}
+	}
+	builder.WriteString("Subscribers: " + strconv.Itoa(len(tracker.GetSubscribers())) + "\n")
+
+	if subscribed {
+		statusMenu.InlineKeyboard = append(statusMenu.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Unsubscribe", "/unsubscribe "+code),
+		))
+		ch.handleCommandMessage(chatID, builder.String(), &statusMenu)
+
+		return nil
+	}
+
+	if tracker.Status.Paused {
+		statusMenu.InlineKeyboard = append(statusMenu.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Resume tracker", "/resume "+code),
+		))
+	}
+
+	if tracker.Status.ExecutionErrors.Total() > 0 {
+		statusMenu.InlineKeyboard = append(statusMenu.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("View errors", "/errors "+code),
+		))
+	}
 
 	statusMenu.InlineKeyboard = append(statusMenu.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonData("Stop tracker", "/stop "+code),
@@ -367,6 +581,9 @@ func (ch *CommandHandler) handleStatus(code string, chatID int64, _ *string) err
 	statusMenu.InlineKeyboard = append(statusMenu.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonData("Change run interval", "/interval "+code),
 	))
+	statusMenu.InlineKeyboard = append(statusMenu.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Edit notification criteria", "/criteria "+code),
+	))
 
 	ch.handleCommandMessage(chatID, builder.String(), &statusMenu)
 
@@ -390,7 +607,7 @@ func (ch *CommandHandler) handleHelp(code string, chatID int64, _ *string) error
 
 	for command, cmd := range ch.commandMap {
 		if !cmd.Hidden && (cmd.Type == generalType || cmd.Type == bothType) {
-			builder.WriteString(fmt.Sprintf(" - /%s - %s\n", command, cmd.DescriptionGeneral))
+			builder.WriteString(fmt.Sprintf(" - /%s - %s%s\n", command, cmd.DescriptionGeneral, adminOnlyMark(cmd.requiresAdmin(""))))
 		}
 	}
 
@@ -398,7 +615,7 @@ func (ch *CommandHandler) handleHelp(code string, chatID int64, _ *string) error
 	builder.WriteString("These require at minimum one parameter - tracker code\n\n")
 	for command, cmd := range ch.commandMap {
 		if !cmd.Hidden && (cmd.Type == trackerType || cmd.Type == bothType) {
-			builder.WriteString(formatCommandWithParams(command, cmd.Params, cmd.DescriptionTracker) + "\n")
+			builder.WriteString(formatCommandWithParams(command, cmd.Params, cmd.DescriptionTracker+adminOnlyMark(cmd.AdminOnly)) + "\n")
 		}
 	}
 
@@ -412,13 +629,14 @@ func (ch *CommandHandler) handleHelp(code string, chatID int64, _ *string) error
 
 /******************Utility******************/
 
-func (ch *CommandHandler) GetActiveTracker(trackerCode string) *Tracker {
+// Returns the tracker running in the chat.
+func (ch *CommandHandler) GetActiveTracker(chatID int64, trackerCode string) *Tracker {
 	// So that only one goroutine can access the trackers at a time
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
 
 	for _, tracker := range ch.runningTrackers {
-		if tracker.Code == trackerCode {
+		if tracker.chatID == chatID && tracker.Code == trackerCode {
 			return tracker
 		}
 	}
@@ -426,6 +644,14 @@ func (ch *CommandHandler) GetActiveTracker(trackerCode string) *Tracker {
 	return nil
 }
 
+// Returns a snapshot of the running trackers that is safe to iterate while trackers are being started or stopped.
+func (ch *CommandHandler) getRunningTrackers() []*Tracker {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	return append([]*Tracker(nil), ch.runningTrackers...)
+}
+
 func (ch *CommandHandler) AddRunningTracker(tracker *Tracker) {
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
@@ -433,18 +659,44 @@ func (ch *CommandHandler) AddRunningTracker(tracker *Tracker) {
 	ch.runningTrackers = append(ch.runningTrackers, tracker)
 }
 
-func (ch *CommandHandler) RemoveRunningTracker(trackerCode string) {
+func (ch *CommandHandler) RemoveRunningTracker(chatID int64, trackerCode string) {
 	ch.mu.Lock()
 	defer ch.mu.Unlock()
 
 	for i, tracker := range ch.runningTrackers {
-		if tracker.Code == trackerCode {
+		if tracker.chatID == chatID && tracker.Code == trackerCode {
 			ch.runningTrackers = append(ch.runningTrackers[:i], ch.runningTrackers[i+1:]...)
+			ch.deleteTrackerState(chatID, trackerCode)
+
 			return
 		}
 	}
 }
 
+// Removes the persisted tracker state so that the tracker is not restored after an application restart.
+func (ch *CommandHandler) deleteTrackerState(chatID int64, trackerCode string) {
+	if err := ch.storage.DeleteTrackerState(chatID, trackerCode); err != nil {
+		log.Printf("[CommandHandler] Error deleting persisted state for tracker '%s': %s", trackerCode, err.Error())
+	}
+}
+
+func formatNextRun(nextRun time.Time) string {
+	if nextRun.IsZero() {
+		return "not planned"
+	}
+
+	return nextRun.Format("02.01.2006 15:04")
+}
+
+// Marks the admin commands in the help menu.
+func adminOnlyMark(adminOnly bool) string {
+	if !adminOnly {
+		return ""
+	}
+
+	return " <i>(admins only)</i>"
+}
+
 func formatCommandWithParams(command string, params []string, description string) string {
 	var builder strings.Builder
 	builder.WriteString(" - /" + command)
@@ -458,15 +710,26 @@ func formatCommandWithParams(command string, params []string, description string
 	return builder.String()
 }
 
-func (ch *CommandHandler) processTrackerStatus(tracker *config.Tracker, menu *tgbotapi.InlineKeyboardMarkup) string {
+func (ch *CommandHandler) processTrackerStatus(tracker *config.Tracker, chatID int64, menu *tgbotapi.InlineKeyboardMarkup) string {
 	menuRow := tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonData("Status ["+tracker.Code+"]", "/status "+tracker.Code),
 	)
 
 	var activeStatus string
-	if ch.GetActiveTracker(tracker.Code) != nil {
+	if activeTracker := ch.GetActiveTracker(chatID, tracker.Code); activeTracker != nil {
 		activeStatus = "active"
+		if activeTracker.Status.Paused {
+			activeStatus = "paused"
+		}
+
+		if subscribers := len(activeTracker.GetSubscribers()); subscribers > 0 {
+			activeStatus += fmt.Sprintf(" (%d subscribers)", subscribers)
+		}
+
 		menuRow = append(menuRow, tgbotapi.NewInlineKeyboardButtonData("Stop ["+tracker.Code+"]", "/stop "+tracker.Code))
+	} else if ch.getSubscribedTracker(chatID, tracker.Code) != nil {
+		activeStatus = "subscribed"
+		menuRow = append(menuRow, tgbotapi.NewInlineKeyboardButtonData("Unsubscribe ["+tracker.Code+"]", "/unsubscribe "+tracker.Code))
 	} else {
 		activeStatus = "inactive"
 		menuRow = append(menuRow, tgbotapi.NewInlineKeyboardButtonData("Start ["+tracker.Code+"]", "/run "+tracker.Code))
@@ -502,6 +765,9 @@ func (ch *CommandHandler) handleCommandMessage(chatID int64, message string, men
 }
 
 func (ch *CommandHandler) GetUserNavigationState(chatID int64) *NavigationState {
+	ch.navigationMu.Lock()
+	defer ch.navigationMu.Unlock()
+
 	if _, exists := ch.Navigation[chatID]; !exists {
 		ch.Navigation[chatID] = &NavigationState{}
 	}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"html"
+	"log"
+	"slices"
+	"strings"
+
+	"pricetrackerbot/config"
+	"pricetrackerbot/helpers"
+)
+
+// Outcome of a tracker configuration reload.
+type reloadResult struct {
+	addedTrackers   []string
+	updatedTrackers []string
+	stoppedTrackers map[int64][]string // Running trackers removed from the configuration grouped by the chats running or subscribed to them
+}
+
+func (ch *CommandHandler) handleReload(code string, chatID int64, _ *string) error {
+	if code != "" {
+		log.Printf("[CommandHandler] code passed to the general-only /reload command")
+		helpers.SendMessageHTML(ch.bot, chatID, "/reload is a general command not specific to any trackers", nil)
+
+		return nil
+	}
+
+	result, err := ch.reloadTrackers()
+	if err != nil {
+		ch.handleCommandMessage(chatID, formatReloadError(err), nil)
+		return err
+	}
+
+	ch.notifyStoppedTrackerOwners(result, chatID)
+	ch.handleCommandMessage(chatID, formatReloadResult(result), nil)
+
+	return nil
+}
+
+// Reloads the configuration whenever the tracker configuration files change. The outcome is reported to the admins unless
+// nothing has changed; the other chats only learn about their trackers being stopped.
+func (ch *CommandHandler) watchTrackerFiles() {
+	ch.config.WatchTrackerFiles(context.Background(), func() {
+		result, err := ch.reloadTrackers()
+		if err != nil {
+			ch.notifyAdmins(formatReloadError(err))
+			return
+		}
+
+		ch.notifyStoppedTrackerOwners(result, 0)
+		if result.hasChanges() {
+			ch.notifyAdmins(formatReloadResult(result))
+		}
+	})
+}
+
+// Sends the message to the private chats of the admins.
+func (ch *CommandHandler) notifyAdmins(message string) {
+	if len(ch.config.AdminUserIDs) == 0 {
+		log.Printf("[CommandHandler] No admins configured to notify about the tracker configuration reload")
+		return
+	}
+
+	for _, userID := range ch.config.AdminUserIDs {
+		helpers.SendMessageHTML(ch.bot, userID, message, nil)
+	}
+}
+
+/*
+Reads the tracker configuration files and reconciles the changes with the current state:
+  - trackers added via the bot and notification criteria overrides are kept on top of the file configuration
+  - running trackers removed from the configuration are stopped
+  - running trackers pick up the changed configuration while keeping their status
+
+Nothing is changed if the configuration files are invalid.
+*/
+func (ch *CommandHandler) reloadTrackers() (*reloadResult, error) {
+	ch.reloadMu.Lock()
+	defer ch.reloadMu.Unlock()
+
+	apiTrackers, scraperTrackers, err := config.LoadTrackerFiles()
+	if err != nil {
+		log.Printf("[CommandHandler] Failed to reload tracker configuration: %s", err.Error())
+		return nil, err
+	}
+
+	previousCodes := make(map[string]bool)
+	for _, tracker := range append(ch.config.GetAPITrackers(), ch.config.GetScraperTrackers()...) {
+		previousCodes[tracker.Code] = true
+	}
+
+	ch.config.ReplaceTrackers(apiTrackers, scraperTrackers)
+	ch.loadStoredTrackers()
+
+	result := &reloadResult{stoppedTrackers: make(map[int64][]string)}
+
+	for _, tracker := range append(ch.config.GetAPITrackers(), ch.config.GetScraperTrackers()...) {
+		if !previousCodes[tracker.Code] {
+			result.addedTrackers = append(result.addedTrackers, tracker.Code)
+		}
+	}
+
+	for _, tracker := range ch.getRunningTrackers() {
+		trackerData := ch.config.GetTrackerData(tracker.Code)
+		if trackerData == nil {
+			tracker.Stop()
+			ch.RemoveRunningTracker(tracker.chatID, tracker.Code)
+			for _, chatID := range tracker.GetChatIDs() {
+				result.stoppedTrackers[chatID] = append(result.stoppedTrackers[chatID], tracker.Code)
+			}
+			log.Printf("[CommandHandler] Stopped tracker '%s' as it has been removed from the configuration", tracker.Code)
+
+			continue
+		}
+
+		changed, err := tracker.UpdateTrackerData(trackerData, DetermineTrackerType(tracker.Code, ch.config))
+		if err != nil {
+			log.Printf("[CommandHandler] Error applying the reloaded configuration to tracker '%s': %s", tracker.Code, err.Error())
+		}
+
+		// The same tracker may be running in several chats
+		if changed && !slices.Contains(result.updatedTrackers, tracker.Code) {
+			result.updatedTrackers = append(result.updatedTrackers, tracker.Code)
+		}
+	}
+
+	log.Printf("[CommandHandler] Tracker configuration reloaded")
+
+	return result, nil
+}
+
+// Lets the owners of stopped trackers know about it; the chat that requested the reload is skipped as it gets the full result anyway.
+func (ch *CommandHandler) notifyStoppedTrackerOwners(result *reloadResult, skipChatID int64) {
+	for chatID, codes := range result.stoppedTrackers {
+		if chatID == skipChatID {
+			continue
+		}
+
+		helpers.SendMessageHTML(ch.bot, chatID, "The following trackers have been removed from the configuration and stopped: <b>"+strings.Join(codes, ", ")+"</b>", nil)
+	}
+}
+
+func (r *reloadResult) hasChanges() bool {
+	return len(r.addedTrackers) > 0 || len(r.updatedTrackers) > 0 || len(r.stoppedTrackers) > 0
+}
+
+func formatReloadResult(result *reloadResult) string {
+	var builder strings.Builder
+	builder.WriteString("<b>Tracker configuration reloaded</b>\n")
+
+	if len(result.addedTrackers) > 0 {
+		builder.WriteString("\nNew trackers: " + strings.Join(result.addedTrackers, ", ") + "\n")
+	}
+
+	if len(result.updatedTrackers) > 0 {
+		builder.WriteString("\nUpdated running trackers: " + strings.Join(result.updatedTrackers, ", ") + "\n")
+	}
+
+	stopped := make([]string, 0)
+	for _, codes := range result.stoppedTrackers {
+		for _, code := range codes {
+			if !slices.Contains(stopped, code) {
+				stopped = append(stopped, code)
+			}
+		}
+	}
+
+	if len(stopped) > 0 {
+		builder.WriteString("\nStopped trackers (removed from the configuration): " + strings.Join(stopped, ", ") + "\n")
+	}
+
+	if !result.hasChanges() {
+		builder.WriteString("\nNo changes affecting the trackers\n")
+	}
+
+	return builder.String()
+}
+
+func formatReloadError(err error) string {
+	return "Failed to reload the tracker configuration, the previous configuration is kept:\n\n<i>" + html.EscapeString(err.Error()) + "</i>"
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"pricetrackerbot/helpers"
+	"pricetrackerbot/utilities"
+)
+
+const cancelCommand = "cancel"
+
+// Sends a message requesting text input; the next plain text message of the chat will be passed to the current command.
+func (ch *CommandHandler) promptUserInput(chatID int64, message string, keyboard *tgbotapi.ReplyKeyboardMarkup) {
+	ch.promptInput(chatID, inputTypeText, message, keyboard)
+}
+
+/*
+Sends a message requesting input of the given type. The next plain text message of the chat is validated against
+the type and appended to the command on top of the navigation stack, so any multi-step command can reuse the prompt
+by handling its own input as the command parameter. The prompt expires after the input timeout.
+*/
+func (ch *CommandHandler) promptInput(chatID int64, inputType string, message string, keyboard *tgbotapi.ReplyKeyboardMarkup) {
+	if keyboard != nil {
+		helpers.SendMessageHTMLWithKeyboard(ch.bot, chatID, message, nil, keyboard)
+	} else {
+		helpers.SendMessageHTML(ch.bot, chatID, message, nil)
+	}
+
+	ch.GetUserNavigationState(chatID).awaitInput(inputType, keyboard)
+}
+
+// Abandons the unfinished conversation of the chat and hides its custom keyboard; returns whether there was one.
+func (ch *CommandHandler) endConversation(chatID int64) bool {
+	prompt, wasActive := ch.GetUserNavigationState(chatID).endConversation()
+	if prompt != nil && prompt.keyboard != nil {
+		helpers.SendMessageRemoveKeyboard(ch.bot, chatID)
+	}
+
+	return wasActive
+}
+
+func (ch *CommandHandler) handleCancel(code string, chatID int64, _ *string) error {
+	if code != "" {
+		helpers.SendMessageHTML(ch.bot, chatID, "/cancel is a general command not specific to any trackers", nil)
+		return nil
+	}
+
+	if !ch.endConversation(chatID) {
+		helpers.SendMessageHTML(ch.bot, chatID, "There is nothing to cancel", nil)
+		return nil
+	}
+
+	helpers.SendMessageHTML(ch.bot, chatID, "Cancelled! Send me a command when you are ready", nil)
+
+	return nil
+}
+
+// Checks whether the input matches the type the prompt expects; returns the explanation shown to the user if not.
+func validateUserInput(inputType string, input string) (string, bool) {
+	switch inputType {
+	case inputTypeInterval:
+		if _, err := utilities.ParseDurationWithDays(input); err != nil {
+			return "Invalid interval value. Available interval types: 'm'(minute), 'h'(hour), 'd'(day)", false
+		}
+	default:
+		if input == "" {
+			return "The answer cannot be empty", false
+		}
+	}
+
+	return "", true
+}
@@ -1,9 +1,30 @@
 package handlers
 
+import (
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	inputTypeText     = "text"
+	inputTypeInterval = "interval"
+	inputTimeout      = 15 * time.Minute // How long the bot waits for the user to reply to a prompt
+)
+
 type NavigationState struct {
 	CallbackMessageID *int
 	BackButtonEnabled bool
 	navigationStack   []*Command
+	trackerDraft      *trackerDraft // Tracker being created via the /add command conversation
+	pendingInput      *inputPrompt  // Prompt the next plain text message of the chat is the answer to
+}
+
+// A prompt for user input; the answer is appended to the command on top of the navigation stack.
+type inputPrompt struct {
+	inputType string
+	keyboard  *tgbotapi.ReplyKeyboardMarkup // Custom keyboard shown with the prompt; removed once answered
+	expiresAt time.Time
 }
 
 func (ns *NavigationState) Push(state *Command) {
@@ -32,3 +53,36 @@ func (ns *NavigationState) Peek() *Command {
 func (ns *NavigationState) IsEmpty() bool {
 	return len(ns.navigationStack) == 0
 }
+
+// Whether the chat has been prompted for input; the prompt may have expired already.
+func (ns *NavigationState) IsAwaitingInput() bool {
+	return ns.pendingInput != nil
+}
+
+func (ns *NavigationState) awaitInput(inputType string, keyboard *tgbotapi.ReplyKeyboardMarkup) {
+	ns.pendingInput = &inputPrompt{
+		inputType: inputType,
+		keyboard:  keyboard,
+		expiresAt: time.Now().Add(inputTimeout),
+	}
+}
+
+// Removes the pending prompt and returns it.
+func (ns *NavigationState) takePendingInput() *inputPrompt {
+	prompt := ns.pendingInput
+	ns.pendingInput = nil
+
+	return prompt
+}
+
+// Abandons any unfinished multi-step conversation; returns the prompt that was pending, if any.
+func (ns *NavigationState) endConversation() (*inputPrompt, bool) {
+	wasActive := ns.pendingInput != nil || ns.trackerDraft != nil
+	ns.trackerDraft = nil
+
+	return ns.takePendingInput(), wasActive
+}
+
+func (p *inputPrompt) isExpired() bool {
+	return time.Now().After(p.expiresAt)
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"pricetrackerbot/helpers"
+	"pricetrackerbot/utilities"
+)
+
+/*
+Subscribes the chat to the notifications of a tracker running in another chat. If several chats run the tracker,
+the user picks one of them; the chosen tracker is passed as the command parameter - the ID of the chat running it.
+Subscribed chats receive the notifications the tracker sends according to the criteria of the chat running it.
+*/
+func (ch *CommandHandler) handleSubscribe(code string, chatID int64, commandParam *string) error {
+	if code == "" {
+		ch.handleCommandMessage(chatID, "No tracker code provided", nil)
+		return errors.New("no tracker code provided")
+	}
+
+	if ch.GetActiveTracker(chatID, code) != nil {
+		ch.handleCommandMessage(chatID, "Tracker '"+code+"' is running in this chat, you already receive its notifications", nil)
+		return nil
+	}
+
+	if ch.getSubscribedTracker(chatID, code) != nil {
+		ch.handleCommandMessage(chatID, "This chat is already subscribed to tracker '"+code+"'", nil)
+		return nil
+	}
+
+	candidates := ch.getTrackerInstances(code)
+	if commandParam != nil {
+		ownerChatID, err := strconv.ParseInt(*commandParam, 10, 64)
+		if err != nil {
+			ch.handleCommandMessage(chatID, "Invalid tracker selection", nil)
+			return err
+		}
+
+		candidates = filterTrackersByChat(candidates, ownerChatID)
+	}
+
+	switch len(candidates) {
+	case 0:
+		menu := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Run tracker", "/run "+code),
+		))
+		ch.handleCommandMessage(chatID, "Tracker '"+code+"' is not running in any chat, you can start it in this chat instead", &menu)
+
+		return errors.New("tracker not running")
+	case 1:
+		tracker := candidates[0]
+		tracker.Subscribe(chatID)
+		log.Printf("[CommandHandler] Chat %d subscribed to tracker '%s' of chat %d", chatID, code, tracker.chatID)
+
+		menu := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Unsubscribe", "/unsubscribe "+code),
+		))
+		ch.handleCommandMessage(chatID, "This chat will now receive the notifications of tracker <b>"+code+"</b>", &menu)
+		helpers.SendMessageHTML(ch.bot, tracker.chatID, fmt.Sprintf("Another chat has subscribed to tracker <b>%s</b>, it now has %d subscriber(s)", code, len(tracker.GetSubscribers())), nil)
+
+		return nil
+	default:
+		menu := tgbotapi.NewInlineKeyboardMarkup()
+		for _, tracker := range candidates {
+			label := fmt.Sprintf("Running since %s, every %s", tracker.Status.StartTimestamp.Format("02.01.2006 15:04"), utilities.DurationToString(tracker.Status.CurrentInterval))
+			menu.InlineKeyboard = append(menu.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("/subscribe %s %d", code, tracker.chatID)),
+			))
+		}
+
+		ch.handleCommandMessage(chatID, "Tracker '"+code+"' is running in several chats, which one would you like to subscribe to?", &menu)
+
+		return nil
+	}
+}
+
+func (ch *CommandHandler) handleUnsubscribe(code string, chatID int64, _ *string) error {
+	if code == "" {
+		ch.handleCommandMessage(chatID, "No tracker code provided", nil)
+		return errors.New("no tracker code provided")
+	}
+
+	tracker := ch.getSubscribedTracker(chatID, code)
+	if tracker == nil {
+		message := "This chat is not subscribed to tracker '" + code + "'"
+		if ch.GetActiveTracker(chatID, code) != nil {
+			message = "Tracker '" + code + "' is running in this chat, use /stop " + code + " to stop receiving its notifications"
+		}
+
+		ch.handleCommandMessage(chatID, message, nil)
+
+		return errors.New("chat not subscribed")
+	}
+
+	tracker.Unsubscribe(chatID)
+	log.Printf("[CommandHandler] Chat %d unsubscribed from tracker '%s' of chat %d", chatID, code, tracker.chatID)
+	ch.handleCommandMessage(chatID, "This chat will no longer receive the notifications of tracker <b>"+code+"</b>", nil)
+
+	return nil
+}
+
+// Returns the tracker of another chat the chat is subscribed to.
+func (ch *CommandHandler) getSubscribedTracker(chatID int64, code string) *Tracker {
+	for _, tracker := range ch.getTrackerInstances(code) {
+		if tracker.IsSubscribed(chatID) {
+			return tracker
+		}
+	}
+
+	return nil
+}
+
+// Returns the trackers with the given code running in any chat.
+func (ch *CommandHandler) getTrackerInstances(code string) []*Tracker {
+	trackers := make([]*Tracker, 0)
+	for _, tracker := range ch.getRunningTrackers() {
+		if tracker.Code == code {
+			trackers = append(trackers, tracker)
+		}
+	}
+
+	return trackers
+}
+
+// Lets the subscribers of a stopped tracker know that they will no longer receive its notifications.
+func (ch *CommandHandler) notifySubscribersStopped(tracker *Tracker) {
+	for _, chatID := range tracker.GetSubscribers() {
+		helpers.SendMessageHTML(ch.bot, chatID, "Tracker <b>"+tracker.Code+"</b> this chat is subscribed to has been stopped", nil)
+	}
+}
+
+func filterTrackersByChat(trackers []*Tracker, chatID int64) []*Tracker {
+	filtered := make([]*Tracker, 0)
+	for _, tracker := range trackers {
+		if tracker.chatID == chatID {
+			filtered = append(filtered, tracker)
+		}
+	}
+
+	return filtered
+}
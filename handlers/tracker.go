@@ -5,72 +5,97 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/url"
+	"reflect"
+	"slices"
+	"sync"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"pricetrackerbot/clients"
 	"pricetrackerbot/config"
-	"pricetrackerbot/helpers"
+	"pricetrackerbot/scheduling"
+	"pricetrackerbot/storage"
 	"pricetrackerbot/utilities"
 )
 
 const (
-	API     = "api"
-	Scraper = "scraper"
+	API     = config.TrackerTypeAPI
+	Scraper = config.TrackerTypeScraper
 )
 
+// Returned when the tracker code is not found in the tracker configurations.
+var ErrUnknownTracker = errors.New("unrecognized tracker code")
+
 type TrackerStatus struct {
 	StartTimestamp    time.Time
 	LastRunTimestamp  time.Time
 	TotalRuns         int
 	LastRecordedValue string
 	CurrentInterval   time.Duration
-	ExecutionErrors   []*TrackerExecutionError
+	NextRunTimestamp  time.Time // Zero if there are no planned runs
+	// Failed runs since the last successful one; the run interval is lengthened and the tracker eventually paused
+	ConsecutiveFailures int
+	Paused              bool
+	ExecutionErrors     *ExecutionErrorLog
+	ProxyFailures       int // Failed runs caused by the proxies rather than the tracked website
 }
 
 type TrackerExecutionError struct {
 	Error     error
+	Category  string // What kind of failure it was, e.g. network or timeout
 	Timestamp time.Time
 }
 
 // Tracker represents a single URL that the bot will track - either through an API or by scraping a website.
 type Tracker struct {
 	Code        string
-	Ticker      *time.Ticker
+	Type        string
+	plan        *scheduling.Plan
 	Context     context.Context
 	Cancel      context.CancelFunc
 	Behavior    TrackerBehavior
 	trackerData *config.Tracker
 	Status      TrackerStatus
 	running     bool
-	chatID      int64
+	firstRun    bool  // Whether the next run is the first one since the tracker has been started
+	chatID      int64 // The chat that started the tracker and manages it
 	bot         *tgbotapi.BotAPI
-	errorLimit  int
+	errorLimit  int // Consecutive failed runs after which the chat is alerted
+	// Whether the chat has been alerted about the current failure streak; reset by a successful run
+	errorStreakNotified bool
+	storage             storage.Storage
+	// Whether the run interval differs from the configured default, i.e. has been set via the /interval command
+	customInterval    bool
+	notificationState *clients.NotificationState
+	// Notification criteria set for the chat via the /criteria command; the configured criteria are used if nil
+	notifyCriteria []config.NotifyCriteria
+	fetchCache     *clients.FetchCache
+	scheduler      *scheduling.Scheduler
+	failurePolicy  failurePolicy
+	// Other chats receiving the notifications of the tracker via the /subscribe command
+	subscribers   []int64
+	subscribersMu sync.Mutex
 }
 
-func CreateTracker(bot *tgbotapi.BotAPI, code string, runInterval time.Duration, config *config.Configuration, chatID int64) (*Tracker, error) {
-	var behavior TrackerBehavior
+func CreateTracker(bot *tgbotapi.BotAPI, code string, runInterval time.Duration, config *config.Configuration, chatID int64, store storage.Storage, cache *clients.FetchCache, scheduler *scheduling.Scheduler) (*Tracker, error) {
 	trackerType := DetermineTrackerType(code, config)
 	trackerData := config.GetTrackerData(code)
 
 	if trackerData == nil {
 		log.Printf("[Tracker] Failed to create a new tracker: %s; no such tracker found in configuration", code)
 
-		return nil, errors.New("uncregonzied tracker code")
+		return nil, ErrUnknownTracker
 	}
 
-	switch trackerType {
-	case API:
-		behavior = NewAPITrackerBehavior(bot)
-	case Scraper:
-		behavior = NewScraperTrackerBehavior(bot)
-	default:
-		return nil, fmt.Errorf("unsupported client type for code: %s", code)
+	behavior, err := newTrackerBehavior(bot, code, trackerType, cache)
+	if err != nil {
+		return nil, err
 	}
 
 	// If runInterval is not provided, use the default interval from the configuration
 	runIntervalToUse := runInterval
 	if runIntervalToUse == 0 {
-		var err error
 		runIntervalToUse, err = utilities.ParseDurationWithDays(trackerData.Interval)
 		if err != nil {
 			log.Printf("[Tracker] Error parsing default configured run interval for tracker '%s': %s", code, err.Error())
@@ -80,9 +105,9 @@ func CreateTracker(bot *tgbotapi.BotAPI, code string, runInterval time.Duration,
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Tracker{
+	tracker := &Tracker{
 		Code:        code,
-		Ticker:      time.NewTicker(runIntervalToUse),
+		Type:        trackerType,
 		trackerData: trackerData,
 		Context:     ctx,
 		Cancel:      cancel,
@@ -90,28 +115,333 @@ func CreateTracker(bot *tgbotapi.BotAPI, code string, runInterval time.Duration,
 		chatID:      chatID,
 		bot:         bot,
 		errorLimit:  config.ErrorNotifyLimit,
+		storage:     store,
 		Status: TrackerStatus{
 			CurrentInterval: runIntervalToUse,
+			ExecutionErrors: NewExecutionErrorLog(config.ErrorHistorySize),
 		},
-	}, nil
+		customInterval:    runInterval != 0,
+		notificationState: clients.NewNotificationState(),
+		fetchCache:        cache,
+		scheduler:         scheduler,
+		failurePolicy:     newFailurePolicy(config),
+	}
+
+	if tracker.plan, err = tracker.createPlan(runIntervalToUse, tracker.customInterval); err != nil {
+		log.Printf("[Tracker] Error creating the run schedule of tracker '%s': %s", code, err.Error())
+		return nil, err
+	}
+
+	return tracker, nil
+}
+
+// Creates the run plan from the interval and the configured schedule; an interval set via the /interval command
+// takes precedence over the cron expressions while the active windows still apply.
+func (t *Tracker) createPlan(interval time.Duration, customInterval bool) (*scheduling.Plan, error) {
+	schedule := t.trackerData.Schedule
+	if schedule != nil && customInterval {
+		schedule = &config.TrackerSchedule{Windows: schedule.Windows}
+	}
+
+	return scheduling.NewPlan(interval, schedule)
+}
+
+// Identifies the tracker in the scheduler; every chat runs its own tracker instance.
+func (t *Tracker) jobID() string {
+	return fmt.Sprintf("%d/%s", t.chatID, t.Code)
+}
+
+// Returns the host the tracker fetches its data from; the runs of the trackers of the same host are rate limited together.
+func (t *Tracker) dataHost() string {
+	dataURL, err := url.Parse(t.trackerData.DataURL)
+	if err != nil {
+		return ""
+	}
+
+	return dataURL.Hostname()
+}
+
+// Describes when the tracker runs, e.g. "every 1h 0m 0s, active 08:00-22:00".
+func (t *Tracker) DescribeSchedule() string {
+	return t.plan.Describe()
+}
+
+// Returns the tracker configuration with the chat specific notification criteria applied.
+func (t *Tracker) getTrackerData() *config.Tracker {
+	if t.notifyCriteria == nil {
+		return t.trackerData
+	}
+
+	trackerData := *t.trackerData
+	trackerData.NotifyCriteria = t.notifyCriteria
+
+	return &trackerData
+}
+
+// Returns the notification criteria the tracker evaluates in its chat.
+func (t *Tracker) GetNotifyCriteria() []config.NotifyCriteria {
+	return t.getTrackerData().NotifyCriteria
+}
+
+// Sets the chat specific notification criteria; nil restores the configured criteria.
+func (t *Tracker) SetNotifyCriteria(criteria []config.NotifyCriteria) {
+	t.notifyCriteria = criteria
 }
 
 func (t *Tracker) executeTrackerLogic() {
+	ctx := t.Context // Taken before the run as a restart replaces it
+	firstRun := t.firstRun
+	t.firstRun = false
 	t.Status.LastRunTimestamp = time.Now()
 	t.Status.TotalRuns++
+	trackerData := t.getTrackerData()
+	t.loadCriteriaHistory(trackerData)
 
-	if value, err := t.Behavior.Execute(t.trackerData, t.chatID); err != nil {
+	result, err := t.executeWithRetries(ctx, trackerData)
+	if err != nil && ctx.Err() != nil {
+		// The tracker has been stopped mid-run; the aborted fetch is not a failure of the tracker
+		log.Printf("[Tracker] Run of tracker '%s' aborted as the tracker has been stopped", t.Code)
+		return
+	}
+
+	if err != nil {
 		log.Printf("[Tracker] Error executing tracker '%s': %s", t.Code, err)
-		t.Status.ExecutionErrors = append(t.Status.ExecutionErrors, &TrackerExecutionError{Error: err, Timestamp: time.Now()})
+		category := categorizeError(err)
+		t.Status.ExecutionErrors.Add(&TrackerExecutionError{Error: err, Category: category, Timestamp: time.Now()})
+		t.Status.ConsecutiveFailures++
+		if category == errorCategoryProxy {
+			t.Status.ProxyFailures++
+		}
 
-		// Notify the user about error accumulation
-		if len(t.Status.ExecutionErrors) >= t.errorLimit {
-			notificationMessage := fmt.Sprintf("More than %d execution errors registered for tracker <b>%s</b>, you should probably take a look at the logs :(", t.errorLimit, t.Code)
-			helpers.SendMessageHTML(t.bot, t.chatID, notificationMessage, nil)
+		// Notify the user about the failure streak once it reaches the limit
+		if t.errorLimit > 0 && !t.errorStreakNotified && t.Status.ConsecutiveFailures >= t.errorLimit {
+			t.notifyFailureStreak(err)
+		}
+
+		// A page disallowed by robots.txt when the tracker is started is not requested again until the owner resumes the tracker
+		if (category == errorCategoryRobots && firstRun) || t.failurePolicy.shouldPause(t.Status.ConsecutiveFailures) {
+			t.pause(err)
 		}
 	} else {
-		t.Status.LastRecordedValue = value
+		if t.errorStreakNotified {
+			t.notifyRecovery()
+		}
+
+		t.Status.ConsecutiveFailures = 0
+		t.Status.LastRecordedValue = result.CurrentValue.String()
+	}
+
+	t.persistState()
+}
+
+// Returns the recorder saving every fetched value in the price history storage. The fetch cache calls it once per
+// tracker code and fetch, so the instances of a tracker in several chats do not duplicate the records. Storage
+// failures are only logged as they have nothing to do with the tracker execution itself.
+func newHistoryRecorder(store storage.Storage) clients.ValueRecorder {
+	if store == nil {
+		return nil
+	}
+
+	return func(trackerData *config.Tracker, value clients.TrackedValue, fetchedAt time.Time) {
+		record := &storage.PriceRecord{
+			TrackerCode: trackerData.Code,
+			Value:       value.Number,
+			Text:        value.Text,
+			Timestamp:   fetchedAt,
+			SourceURL:   trackerData.DataURL,
+		}
+
+		if err := store.SavePriceRecord(record); err != nil {
+			log.Printf("[Tracker] Error saving price record for tracker '%s': %s", trackerData.Code, err)
+		}
+	}
+}
+
+// Provides the notification state with the price history the historical notification criteria of the tracker need.
+// The all-time extremes are loaded once and then kept up to date by the criteria evaluation itself.
+func (t *Tracker) loadCriteriaHistory(trackerData *config.Tracker) {
+	period, allTime, err := clients.RequiredHistory(trackerData.NotifyCriteria)
+	if err != nil {
+		log.Printf("[Tracker] Error determining the price history needed by tracker '%s': %s", t.Code, err)
+		return
+	}
+
+	if !allTime {
+		t.notificationState.AllTimeLow, t.notificationState.AllTimeHigh = nil, nil
+	}
+
+	if period == 0 {
+		t.notificationState.History = nil
+	}
+
+	if t.storage == nil || (period == 0 && (!allTime || t.notificationState.AllTimeLow != nil)) {
+		return
+	}
+
+	if allTime && t.notificationState.AllTimeLow == nil {
+		records, err := t.storage.GetPriceHistory(t.Code, time.Time{})
+		if err != nil {
+			log.Printf("[Tracker] Error loading price history for tracker '%s': %s", t.Code, err)
+			return
+		}
+
+		t.notificationState.LoadAllTimeExtremes(toHistoryPoints(records))
+	}
+
+	if period > 0 {
+		records, err := t.storage.GetPriceHistory(t.Code, time.Now().Add(-period))
+		if err != nil {
+			log.Printf("[Tracker] Error loading price history for tracker '%s': %s", t.Code, err)
+			return
+		}
+
+		t.notificationState.History = toHistoryPoints(records)
+	}
+}
+
+func toHistoryPoints(records []*storage.PriceRecord) []clients.HistoryPoint {
+	points := make([]clients.HistoryPoint, 0, len(records))
+	for _, record := range records {
+		points = append(points, clients.HistoryPoint{Value: record.Value, Timestamp: record.Timestamp})
+	}
+
+	return points
+}
+
+// Saves the tracker state so that the tracker can be restored after an application restart.
+func (t *Tracker) persistState() {
+	// A stopped tracker must not be persisted, otherwise a run finishing after the stop would resurrect it on restart
+	if t.storage == nil || !t.running {
+		return
+	}
+
+	state := &storage.TrackerState{
+		TrackerCode:         t.Code,
+		ChatID:              t.chatID,
+		StartTimestamp:      t.Status.StartTimestamp,
+		LastRunTimestamp:    t.Status.LastRunTimestamp,
+		TotalRuns:           t.Status.TotalRuns,
+		LastRecordedValue:   t.Status.LastRecordedValue,
+		NotificationState:   toStoredNotificationState(t.notificationState),
+		Subscribers:         t.GetSubscribers(),
+		Paused:              t.Status.Paused,
+		ConsecutiveFailures: t.Status.ConsecutiveFailures,
+		ErrorStreakNotified: t.errorStreakNotified,
+	}
+
+	if t.customInterval {
+		state.Interval = t.Status.CurrentInterval
+	}
+
+	if err := t.storage.SaveTrackerState(state); err != nil {
+		log.Printf("[Tracker] Error saving state for tracker '%s': %s", t.Code, err)
+	}
+}
+
+// Restores the status values of a tracker that was running before an application restart.
+func (t *Tracker) RestoreState(state *storage.TrackerState) {
+	t.Status.StartTimestamp = state.StartTimestamp
+	t.Status.LastRunTimestamp = state.LastRunTimestamp
+	t.Status.TotalRuns = state.TotalRuns
+	t.Status.LastRecordedValue = state.LastRecordedValue
+	t.Status.Paused = state.Paused
+	t.Status.ConsecutiveFailures = state.ConsecutiveFailures
+	t.errorStreakNotified = state.ErrorStreakNotified
+
+	if state.NotificationState != nil {
+		t.notificationState = fromStoredNotificationState(state.NotificationState)
+	}
+
+	t.subscribersMu.Lock()
+	t.subscribers = append([]int64(nil), state.Subscribers...)
+	t.subscribersMu.Unlock()
+}
+
+func toStoredNotificationState(state *clients.NotificationState) *storage.NotificationState {
+	stored := &storage.NotificationState{
+		Criteria:      make(map[string]*storage.CriteriaState, len(state.Criteria)),
+		PreviousValue: state.PreviousValue,
+		BaselineValue: state.BaselineValue,
+		PreviousText:  state.PreviousText,
+		BaselineText:  state.BaselineText,
+		AllTimeLow:    state.AllTimeLow,
+		AllTimeHigh:   state.AllTimeHigh,
+	}
+
+	for key, criteriaState := range state.Criteria {
+		stored.Criteria[key] = &storage.CriteriaState{Fulfilled: criteriaState.Fulfilled, LastNotified: criteriaState.LastNotified}
+	}
+
+	return stored
+}
+
+func fromStoredNotificationState(stored *storage.NotificationState) *clients.NotificationState {
+	state := clients.NewNotificationState()
+	state.PreviousValue = stored.PreviousValue
+	state.BaselineValue = stored.BaselineValue
+	state.PreviousText = stored.PreviousText
+	state.BaselineText = stored.BaselineText
+	state.AllTimeLow = stored.AllTimeLow
+	state.AllTimeHigh = stored.AllTimeHigh
+
+	for key, criteriaState := range stored.Criteria {
+		state.Criteria[key] = &clients.CriteriaState{Fulfilled: criteriaState.Fulfilled, LastNotified: criteriaState.LastNotified}
 	}
+
+	return state
+}
+
+// Adds a chat to the tracker notification recipients; returns false if the chat already receives them.
+func (t *Tracker) Subscribe(chatID int64) bool {
+	t.subscribersMu.Lock()
+	if chatID == t.chatID || slices.Contains(t.subscribers, chatID) {
+		t.subscribersMu.Unlock()
+		return false
+	}
+
+	t.subscribers = append(t.subscribers, chatID)
+	t.subscribersMu.Unlock()
+
+	t.persistState()
+
+	return true
+}
+
+// Removes a chat from the tracker notification recipients; returns false if the chat is not subscribed.
+func (t *Tracker) Unsubscribe(chatID int64) bool {
+	t.subscribersMu.Lock()
+	index := slices.Index(t.subscribers, chatID)
+	if index < 0 {
+		t.subscribersMu.Unlock()
+		return false
+	}
+
+	t.subscribers = slices.Delete(t.subscribers, index, index+1)
+	t.subscribersMu.Unlock()
+
+	t.persistState()
+
+	return true
+}
+
+func (t *Tracker) IsSubscribed(chatID int64) bool {
+	t.subscribersMu.Lock()
+	defer t.subscribersMu.Unlock()
+
+	return slices.Contains(t.subscribers, chatID)
+}
+
+// Returns the chats subscribed to the tracker, not including the chat running it.
+func (t *Tracker) GetSubscribers() []int64 {
+	t.subscribersMu.Lock()
+	defer t.subscribersMu.Unlock()
+
+	return append([]int64(nil), t.subscribers...)
+}
+
+// Returns all the chats receiving the tracker notifications - the chat running it followed by the subscribers.
+func (t *Tracker) GetChatIDs() []int64 {
+	return append([]int64{t.chatID}, t.GetSubscribers()...)
 }
 
 func (t *Tracker) Start() {
@@ -122,28 +452,29 @@ func (t *Tracker) Start() {
 	// Recreate context for when the tracker is being restarted after interval update
 	if t.Context.Err() != nil {
 		t.Context, t.Cancel = context.WithCancel(context.Background())
-	} else {
+	} else if t.Status.StartTimestamp.IsZero() {
 		t.Status.StartTimestamp = time.Now() // Set the start timestamp only when the tracker is started for the first time
 	}
 
 	t.running = true
+	t.firstRun = true
+	t.persistState()
 
-	go func() {
-		defer func() { t.running = false }()
-
-		// Execute immediately on start
-		t.executeTrackerLogic()
+	// Paused trackers are kept but not run until resumed
+	if !t.Status.Paused {
+		t.schedule(t.plan.RunsImmediately(time.Now())) // Immediately on start unless the tracker runs at certain times only
+	}
+}
 
-		for {
-			select {
-			case <-t.Ticker.C:
-				t.executeTrackerLogic()
-			case <-t.Context.Done():
-				log.Printf("[Tracker] Stopping tracker '%s'", t.Code)
-				return
-			}
-		}
-	}()
+func (t *Tracker) schedule(runImmediately bool) {
+	t.scheduler.Schedule(&scheduling.Job{
+		ID:        t.jobID(),
+		Plan:      t.plan,
+		Host:      t.dataHost,
+		Run:       t.executeTrackerLogic,
+		OnPlanned: func(next time.Time) { t.Status.NextRunTimestamp = next },
+		Backoff:   t.failureBackoff,
+	}, runImmediately)
 }
 
 func (t *Tracker) Stop() {
@@ -151,30 +482,96 @@ func (t *Tracker) Stop() {
 		return
 	}
 
-	t.Ticker.Stop()
+	t.scheduler.Remove(t.jobID())
 	t.Cancel()
 	t.running = false
+	log.Printf("[Tracker] Stopped tracker '%s' of chat %d", t.Code, t.chatID)
+}
+
+// Sets the run interval of the tracker via the /interval command; the tracker is left unchanged if the interval is invalid.
+func (t *Tracker) UpdateInterval(newInterval time.Duration) error {
+	return t.restartWithInterval(newInterval, true)
 }
 
-func (t *Tracker) UpdateInterval(newInterval time.Duration) {
-	t.Stop()
-	time.Sleep(1 * time.Second)
-	t.Ticker = time.NewTicker(newInterval)
+// Replaces the run plan and reschedules the running tracker; the plan is validated before anything is changed.
+func (t *Tracker) restartWithInterval(newInterval time.Duration, customInterval bool) error {
+	plan, err := t.createPlan(newInterval, customInterval)
+	if err != nil {
+		return err
+	}
+
 	t.Status.CurrentInterval = newInterval
-	t.Start()
+	t.customInterval = customInterval
+	t.plan = plan
+
+	// A fetch in progress finishes with its own run; only the planned runs change
+	if t.running && !t.Status.Paused {
+		t.schedule(plan.RunsImmediately(time.Now()))
+	}
+	t.persistState()
+
+	return nil
 }
 
-func DetermineTrackerType(trackerCode string, config *config.Configuration) string {
-	for _, tracker := range config.APITrackers {
-		if tracker.Code == trackerCode {
-			return API
+// Swaps the tracker configuration after the configuration files have been reloaded while keeping the tracker status.
+// A changed configured interval is only applied if it has not been overridden via the /interval command;
+// a changed schedule is always applied.
+// Returns whether anything has changed.
+func (t *Tracker) UpdateTrackerData(trackerData *config.Tracker, trackerType string) (bool, error) {
+	if trackerType == t.Type && reflect.DeepEqual(t.trackerData, trackerData) {
+		t.trackerData = trackerData
+		return false, nil
+	}
+
+	if trackerType != t.Type {
+		behavior, err := newTrackerBehavior(t.bot, t.Code, trackerType, t.fetchCache)
+		if err != nil {
+			return false, err
 		}
+
+		t.Behavior = behavior
+		t.Type = trackerType
 	}
 
-	for _, tracker := range config.ScraperTrackers {
-		if tracker.Code == trackerCode {
-			return Scraper
+	intervalChanged := t.trackerData.Interval != trackerData.Interval && !t.customInterval
+	scheduleChanged := !reflect.DeepEqual(t.trackerData.Schedule, trackerData.Schedule)
+	t.trackerData = trackerData
+
+	if intervalChanged || scheduleChanged {
+		newInterval := t.Status.CurrentInterval
+		if intervalChanged {
+			var err error
+			if newInterval, err = utilities.ParseDurationWithDays(trackerData.Interval); err != nil {
+				return true, err
+			}
 		}
+
+		if err := t.restartWithInterval(newInterval, t.customInterval); err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
+
+func newTrackerBehavior(bot *tgbotapi.BotAPI, code string, trackerType string, cache *clients.FetchCache) (TrackerBehavior, error) {
+	switch trackerType {
+	case API:
+		return NewAPITrackerBehavior(bot, cache), nil
+	case Scraper:
+		return NewScraperTrackerBehavior(bot, cache), nil
+	default:
+		return nil, fmt.Errorf("unsupported client type for code: %s", code)
+	}
+}
+
+func DetermineTrackerType(trackerCode string, config *config.Configuration) string {
+	if config.GetAPITrackerData(trackerCode) != nil {
+		return API
+	}
+
+	if config.GetScraperTrackerData(trackerCode) != nil {
+		return Scraper
 	}
 
 	return ""
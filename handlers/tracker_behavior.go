@@ -1,7 +1,7 @@
 package handlers
 
 import (
-	"fmt"
+	"context"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"pricetrackerbot/clients"
@@ -17,7 +17,8 @@ to add new ways of fetching data without changing the existing code.
 It is NOT meant for implementing the data fetching logic itself - that will be done in the clients.
 */
 type TrackerBehavior interface {
-	Execute(trackerData *config.Tracker, chatID int64) (string, error)
+	// Fetches the tracked value and sends the resulting notification to all the given chats; cancelling the context aborts the fetch.
+	Execute(ctx context.Context, trackerData *config.Tracker, chatIDs []int64, state *clients.NotificationState) (*clients.DataResult, error)
 }
 
 type APITrackerBehavior struct {
@@ -25,25 +26,27 @@ type APITrackerBehavior struct {
 	client *clients.PublicAPIClient
 }
 
-func NewAPITrackerBehavior(bot *tgbotapi.BotAPI) *APITrackerBehavior {
+func NewAPITrackerBehavior(bot *tgbotapi.BotAPI, cache *clients.FetchCache) *APITrackerBehavior {
 	return &APITrackerBehavior{
 		bot:    bot,
-		client: clients.NewPublicAPIClient(),
+		client: clients.NewPublicAPIClient(cache),
 	}
 }
 
-func (tb *APITrackerBehavior) Execute(trackerData *config.Tracker, chatID int64) (string, error) {
-	result, err := tb.client.FetchAndExtractData(trackerData)
+func (tb *APITrackerBehavior) Execute(ctx context.Context, trackerData *config.Tracker, chatIDs []int64, state *clients.NotificationState) (*clients.DataResult, error) {
+	result, err := tb.client.FetchAndExtractData(ctx, trackerData, state)
 	if err != nil {
 		// Notify the user? Add to some failure statistics?
-		return "", err
+		return nil, err
 	}
 
 	if result.NotificationMessage != "" {
-		helpers.SendMessageHTML(tb.bot, chatID, result.NotificationMessage, nil)
+		for _, chatID := range chatIDs {
+			helpers.SendMessageHTML(tb.bot, chatID, result.NotificationMessage, nil)
+		}
 	}
 
-	return fmt.Sprintf("%.2f", result.CurrentValue), nil
+	return result, nil
 }
 
 type ScraperTrackerBehavior struct {
@@ -51,24 +54,25 @@ type ScraperTrackerBehavior struct {
 	client *clients.ScraperClient
 }
 
-func NewScraperTrackerBehavior(bot *tgbotapi.BotAPI) *ScraperTrackerBehavior {
+func NewScraperTrackerBehavior(bot *tgbotapi.BotAPI, cache *clients.FetchCache) *ScraperTrackerBehavior {
 	return &ScraperTrackerBehavior{
 		bot:    bot,
-		client: clients.NewScraperClient(),
+		client: clients.NewScraperClient(cache),
 	}
 }
 
-func (tb *ScraperTrackerBehavior) Execute(trackerData *config.Tracker, chatID int64) (string, error) {
-	result, err := tb.client.FetchAndExtractData(trackerData)
+func (tb *ScraperTrackerBehavior) Execute(ctx context.Context, trackerData *config.Tracker, chatIDs []int64, state *clients.NotificationState) (*clients.DataResult, error) {
+	result, err := tb.client.FetchAndExtractData(ctx, trackerData, state)
 	if err != nil {
 		// Notify the user? Add to some failure statistics?
-		return "", err
+		return nil, err
 	}
 
 	if result.NotificationMessage != "" {
-		helpers.SendMessageHTML(tb.bot, chatID, result.NotificationMessage, nil)
+		for _, chatID := range chatIDs {
+			helpers.SendMessageHTML(tb.bot, chatID, result.NotificationMessage, nil)
+		}
 	}
 
-	return fmt.Sprintf("%.2f", result.CurrentValue), nil
-	// return "", nil
+	return result, nil
 }
@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"pricetrackerbot/clients"
+	"pricetrackerbot/helpers"
+	"pricetrackerbot/services"
+)
+
+// Execution error categories.
+const (
+	errorCategoryNetwork    = "network"
+	errorCategoryTimeout    = "timeout"
+	errorCategoryHTTPStatus = "http status"
+	errorCategoryExtraction = "extraction"
+	errorCategoryParse      = "parse"
+	errorCategoryCriteria   = "criteria"
+	errorCategoryConfig     = "configuration"
+	errorCategoryProxy      = "proxy"
+	errorCategoryRobots     = "robots"
+	errorCategoryOther      = "other"
+)
+
+const clearErrorsAction = "clear"
+
+// Bounded log of the most recent execution errors of a tracker; the oldest errors are overwritten by the new ones.
+type ExecutionErrorLog struct {
+	mu      sync.Mutex
+	entries []*TrackerExecutionError
+	next    int // Index the next error is written to
+	total   int // Errors registered since the log was last cleared, including the overwritten ones
+}
+
+func NewExecutionErrorLog(size int) *ExecutionErrorLog {
+	return &ExecutionErrorLog{entries: make([]*TrackerExecutionError, 0, max(size, 1))}
+}
+
+func (l *ExecutionErrorLog) Add(executionError *TrackerExecutionError) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.entries) < cap(l.entries) {
+		l.entries = append(l.entries, executionError)
+	} else {
+		l.entries[l.next] = executionError
+	}
+
+	l.next = (l.next + 1) % cap(l.entries)
+	l.total++
+}
+
+// Returns the kept errors, the oldest first.
+func (l *ExecutionErrorLog) Recent() []*TrackerExecutionError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.entries) < cap(l.entries) {
+		return append([]*TrackerExecutionError(nil), l.entries...)
+	}
+
+	return append(append([]*TrackerExecutionError(nil), l.entries[l.next:]...), l.entries[:l.next]...)
+}
+
+// Returns the most recent error, nil if there is none.
+func (l *ExecutionErrorLog) Last() *TrackerExecutionError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.entries) == 0 {
+		return nil
+	}
+
+	return l.entries[(l.next+len(l.entries)-1)%len(l.entries)]
+}
+
+// Returns the number of errors registered since the log was last cleared.
+func (l *ExecutionErrorLog) Total() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.total
+}
+
+func (l *ExecutionErrorLog) Clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = l.entries[:0]
+	l.next = 0
+	l.total = 0
+}
+
+// Determines what kind of failure an execution error is, so that the users can tell e.g. an unavailable site from a changed page.
+func categorizeError(err error) string {
+	var (
+		networkErr    *services.NetworkError
+		timeoutErr    *services.TimeoutError
+		statusErr     *services.HTTPStatusError
+		extractionErr *clients.ExtractionError
+		parseErr      *clients.ParseError
+		criteriaErr   *clients.CriteriaError
+		configErr     *clients.RequestConfigError
+		proxyErr      *services.ProxyError
+		robotsErr     *services.RobotsBlockedError
+	)
+
+	switch {
+	// Checked first as proxy failures may be network errors or timeouts too
+	case errors.As(err, &proxyErr), errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusProxyAuthRequired:
+		return errorCategoryProxy
+	case errors.As(err, &timeoutErr), errors.Is(err, context.DeadlineExceeded):
+		return errorCategoryTimeout
+	case errors.As(err, &networkErr):
+		return errorCategoryNetwork
+	case errors.As(err, &statusErr):
+		return errorCategoryHTTPStatus
+	case errors.As(err, &extractionErr):
+		return errorCategoryExtraction
+	case errors.As(err, &parseErr):
+		return errorCategoryParse
+	case errors.As(err, &criteriaErr):
+		return errorCategoryCriteria
+	case errors.As(err, &configErr):
+		return errorCategoryConfig
+	case errors.As(err, &robotsErr):
+		return errorCategoryRobots
+	default:
+		return errorCategoryOther
+	}
+}
+
+// Whether the failure may go away by itself, so that the fetch is worth retrying right away. Error responses, e.g. 429 or 5xx,
+// have already been retried by the HTTP client honoring their Retry-After, so retrying them again would only multiply the requests.
+func isTransientError(err error) bool {
+	var statusErr *services.HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return false
+	}
+
+	category := categorizeError(err)
+
+	// Another proxy may be picked for the retry
+	return category == errorCategoryNetwork || category == errorCategoryTimeout || category == errorCategoryProxy
+}
+
+// Suggests what to do about the error, e.g. a missing page needs a different URL while a missing element a different extraction path.
+func errorHint(err error) string {
+	var statusErr *services.HTTPStatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone:
+			return "The page does not exist anymore, the data URL of the tracker may have changed"
+		case statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden:
+			return "The website refuses the requests of the bot"
+		case statusErr.IsTemporary():
+			return "The website is unavailable or limiting the requests at the moment"
+		}
+	}
+
+	var tooLargeErr *services.ResponseTooLargeError
+	if errors.As(err, &tooLargeErr) {
+		return "The response is larger than the HTTP_MAX_BODY_SIZE setting allows"
+	}
+
+	switch categorizeError(err) {
+	case errorCategoryNetwork, errorCategoryTimeout:
+		return "The website could not be reached, it may be down at the moment"
+	case errorCategoryProxy:
+		return "The proxy could not be reached or rejected the request, check the proxy settings"
+	case errorCategoryExtraction:
+		return "The tracked value was not found, the page layout may have changed and the data extraction path needs updating"
+	case errorCategoryParse:
+		return "The tracked value is not in the expected format, the data extraction path may point to a wrong element"
+	case errorCategoryCriteria:
+		return "The notification criteria of the tracker are misconfigured"
+	case errorCategoryConfig:
+		return "The request settings of the tracker are misconfigured, e.g. a secret is missing from the environment"
+	case errorCategoryRobots:
+		return "The website's robots.txt disallows scraping the page; the tracker cannot run unless the website allows it " +
+			"or robots.txt is ignored for the domain"
+	default:
+		return ""
+	}
+}
+
+// Formats the error for the messages sent to the users, together with the hint if there is one.
+func describeError(err error) string {
+	description := html.EscapeString(err.Error())
+	if hint := errorHint(err); hint != "" {
+		description += "\n" + hint
+	}
+
+	return description
+}
+
+// Alerts the chat running the tracker once per failure streak.
+func (t *Tracker) notifyFailureStreak(lastError error) {
+	t.errorStreakNotified = true
+
+	menu := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("View errors", "/errors "+t.Code),
+	))
+	message := fmt.Sprintf("Tracker <b>%s</b> has failed %d times in a row, you will be notified once it works again\n\nLast error: %s",
+		t.Code, t.Status.ConsecutiveFailures, describeError(lastError))
+	helpers.SendMessageHTMLWithMenu(t.bot, t.chatID, message, nil, &menu)
+}
+
+// Lets the chat alerted about a failure streak know that the tracker works again.
+func (t *Tracker) notifyRecovery() {
+	t.errorStreakNotified = false
+
+	message := fmt.Sprintf("Tracker <b>%s</b> works again after %d failed runs", t.Code, t.Status.ConsecutiveFailures)
+	helpers.SendMessageHTML(t.bot, t.chatID, message, nil)
+}
+
+func (ch *CommandHandler) handleResume(code string, chatID int64, _ *string) error {
+	if code == "" {
+		ch.handleCommandMessage(chatID, "No tracker code provided", nil)
+		return errors.New("no tracker code provided")
+	}
+
+	tracker := ch.GetActiveTracker(chatID, code)
+	if tracker == nil {
+		ch.handleCommandMessage(chatID, "Tracker <b>"+code+"</b> is not running in this chat", nil)
+		return errors.New("tracker not found")
+	}
+
+	if !tracker.Resume() {
+		ch.handleCommandMessage(chatID, "Tracker <b>"+code+"</b> is not paused", nil)
+		return nil
+	}
+
+	ch.handleCommandMessage(chatID, "Tracker <b>"+code+"</b> has been resumed, you will be alerted again if it keeps failing", nil)
+
+	return nil
+}
+
+/*
+Lists the recent execution errors of a tracker running in the chat or the one the chat is subscribed to, with their
+timestamps and categories. The chat running the tracker can also clear the errors - passed as the 'clear' parameter.
+*/
+func (ch *CommandHandler) handleErrors(code string, chatID int64, commandParam *string) error {
+	if code == "" {
+		ch.handleCommandMessage(chatID, "No tracker code provided", nil)
+		return errors.New("no tracker code provided")
+	}
+
+	tracker := ch.GetActiveTracker(chatID, code)
+	owner := tracker != nil
+	if tracker == nil {
+		tracker = ch.getSubscribedTracker(chatID, code)
+	}
+
+	if tracker == nil {
+		ch.handleCommandMessage(chatID, "Tracker <b>"+code+"</b> is not active", nil)
+		return errors.New("tracker not found")
+	}
+
+	if commandParam != nil && *commandParam == clearErrorsAction {
+		if !owner {
+			ch.handleCommandMessage(chatID, "Only the chat running tracker <b>"+code+"</b> can clear its errors", nil)
+			return errors.New("chat not running the tracker")
+		}
+
+		tracker.Status.ExecutionErrors.Clear()
+		ch.handleCommandMessage(chatID, "Execution errors of tracker <b>"+code+"</b> cleared", nil)
+
+		return nil
+	}
+
+	executionErrors := tracker.Status.ExecutionErrors.Recent()
+	if len(executionErrors) == 0 {
+		ch.handleCommandMessage(chatID, "No execution errors registered for tracker <b>"+code+"</b>", nil)
+		return nil
+	}
+
+	var builder strings.Builder
+	builder.WriteString("<b>Recent execution errors of tracker " + code + "</b>\n")
+	builder.WriteString(fmt.Sprintf("Showing the last %d of %d errors, the newest last\n\n", len(executionErrors), tracker.Status.ExecutionErrors.Total()))
+
+	for _, executionError := range executionErrors {
+		builder.WriteString(fmt.Sprintf("%s [%s] %s\n",
+			executionError.Timestamp.Format("02.01.2006 15:04:05"), executionError.Category, html.EscapeString(executionError.Error.Error())))
+	}
+
+	if !owner {
+		ch.handleCommandMessage(chatID, builder.String(), nil)
+		return nil
+	}
+
+	row := tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Clear errors", "/errors "+code+" "+clearErrorsAction))
+	if tracker.Status.Paused {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("Resume", "/resume "+code))
+	}
+
+	menu := tgbotapi.NewInlineKeyboardMarkup(row)
+	ch.handleCommandMessage(chatID, builder.String(), &menu)
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestExecutionErrorLog(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		added      int
+		clear      bool
+		wantRecent []string
+		wantTotal  int
+	}{
+		{name: "empty", size: 3, wantRecent: nil, wantTotal: 0},
+		{name: "not full", size: 3, added: 2, wantRecent: []string{"1", "2"}, wantTotal: 2},
+		{name: "full", size: 3, added: 3, wantRecent: []string{"1", "2", "3"}, wantTotal: 3},
+		{name: "oldest overwritten", size: 3, added: 5, wantRecent: []string{"3", "4", "5"}, wantTotal: 5},
+		{name: "size below one keeps the last error", size: 0, added: 2, wantRecent: []string{"2"}, wantTotal: 2},
+		{name: "cleared", size: 3, added: 4, clear: true, wantRecent: nil, wantTotal: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errorLog := NewExecutionErrorLog(tt.size)
+			for i := 1; i <= tt.added; i++ {
+				errorLog.Add(&TrackerExecutionError{Error: errors.New(strconv.Itoa(i))})
+			}
+
+			if tt.clear {
+				errorLog.Clear()
+			}
+
+			var recent []string
+			for _, executionError := range errorLog.Recent() {
+				recent = append(recent, executionError.Error.Error())
+			}
+
+			if !slices.Equal(recent, tt.wantRecent) {
+				t.Errorf("Recent() = %v, want %v", recent, tt.wantRecent)
+			}
+
+			if got := errorLog.Total(); got != tt.wantTotal {
+				t.Errorf("Total() = %d, want %d", got, tt.wantTotal)
+			}
+
+			var wantLast string
+			if len(tt.wantRecent) > 0 {
+				wantLast = tt.wantRecent[len(tt.wantRecent)-1]
+			}
+
+			var last string
+			if executionError := errorLog.Last(); executionError != nil {
+				last = executionError.Error.Error()
+			}
+
+			if last != wantLast {
+				t.Errorf("Last() = %q, want %q", last, wantLast)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"pricetrackerbot/clients"
+	"pricetrackerbot/config"
+	"pricetrackerbot/helpers"
+	"pricetrackerbot/services"
+)
+
+// Caps the exponent of the failure backoff so that the interval multiplication cannot overflow.
+const maxBackoffExponent = 10
+
+// How a tracker reacts to failed runs.
+type failurePolicy struct {
+	retryAttempts  int           // Retries of a failed fetch within a run
+	retryDelay     time.Duration // Delay before the first retry; doubled for every next one
+	backoffMax     time.Duration // Maximum delay added to the run interval after consecutive failed runs
+	pauseThreshold int           // Consecutive failed runs after which the tracker is paused; never if 0
+}
+
+func newFailurePolicy(config *config.Configuration) failurePolicy {
+	return failurePolicy{
+		retryAttempts:  config.RetryAttempts,
+		retryDelay:     config.RetryDelay,
+		backoffMax:     config.FailureBackoffMax,
+		pauseThreshold: config.AutoPauseThreshold,
+	}
+}
+
+func (p failurePolicy) shouldPause(consecutiveFailures int) bool {
+	return p.pauseThreshold > 0 && consecutiveFailures >= p.pauseThreshold
+}
+
+// Executes the tracker, retrying fetches failed for transient reasons with an exponentially growing delay.
+// Errors that a retry would not fix, e.g. a missing page or a changed page layout, are returned right away. Stopping the tracker,
+// i.e. cancelling the context, aborts the running fetch and ends the retries.
+func (t *Tracker) executeWithRetries(ctx context.Context, trackerData *config.Tracker) (*clients.DataResult, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := t.Behavior.Execute(ctx, trackerData, t.GetChatIDs(), t.notificationState)
+		if err != nil && ctx.Err() != nil {
+			return nil, err
+		}
+
+		if err == nil || attempt >= t.failurePolicy.retryAttempts || !isTransientError(err) {
+			return result, err
+		}
+
+		delay := t.failurePolicy.retryDelay << attempt
+		log.Printf("[Tracker] Tracker '%s' run failed, retrying in %s: %s", t.Code, delay, err)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, err
+		}
+	}
+}
+
+// Returns the delay added to the run interval after consecutive failed runs - the interval is doubled with every failed run
+// up to the configured maximum, so that broken trackers do not keep sending requests at the normal rate.
+func (t *Tracker) failureBackoff() time.Duration {
+	if t.Status.ConsecutiveFailures == 0 || t.failurePolicy.backoffMax <= 0 {
+		return 0
+	}
+
+	multiplier := time.Duration(1)<<min(t.Status.ConsecutiveFailures, maxBackoffExponent) - 1
+
+	return min(t.Status.CurrentInterval*multiplier, t.failurePolicy.backoffMax)
+}
+
+// Stops running the tracker after too many consecutive failures or a page disallowed by robots.txt and offers its owner
+// to resume it or view the errors.
+func (t *Tracker) pause(lastError error) {
+	t.scheduler.Remove(t.jobID())
+	t.Status.Paused = true
+	t.Status.NextRunTimestamp = time.Time{}
+	t.persistState()
+
+	reason := "after " + strconv.Itoa(t.Status.ConsecutiveFailures) + " consecutive failed runs"
+	var robotsErr *services.RobotsBlockedError
+	if errors.As(lastError, &robotsErr) {
+		reason = "as the website's robots.txt disallows scraping its page"
+	}
+
+	log.Printf("[Tracker] Tracker '%s' of chat %d paused %s", t.Code, t.chatID, reason)
+
+	menu := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Resume", "/resume "+t.Code),
+		tgbotapi.NewInlineKeyboardButtonData("View errors", "/errors "+t.Code),
+	))
+	message := "Tracker <b>" + t.Code + "</b> has been paused " + reason + "\n\nLast error: " + describeError(lastError)
+	helpers.SendMessageHTMLWithMenu(t.bot, t.chatID, message, nil, &menu)
+}
+
+// Runs a paused tracker again right away; returns false if the tracker is not paused.
+func (t *Tracker) Resume() bool {
+	if !t.Status.Paused {
+		return false
+	}
+
+	// Resuming ends the failure streak; a new streak alerts the chat again instead of reporting a recovery from zero failures
+	t.Status.Paused = false
+	t.Status.ConsecutiveFailures = 0
+	t.errorStreakNotified = false
+	t.persistState()
+	t.schedule(true)
+
+	return true
+}
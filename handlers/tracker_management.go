@@ -0,0 +1,607 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"pricetrackerbot/config"
+	"pricetrackerbot/helpers"
+	"pricetrackerbot/storage"
+	"pricetrackerbot/utilities"
+)
+
+const (
+	skipInput = "skip"
+	doneInput = "done"
+)
+
+// Actions of the /criteria command.
+const (
+	criteriaActionAdd    = "add"
+	criteriaActionDelete = "delete"
+	criteriaActionReset  = "reset"
+)
+
+// Steps of the /add command conversation in the order they are asked.
+const (
+	draftStepCode = iota
+	draftStepType
+	draftStepDataURL
+	draftStepViewURL
+	draftStepExtractionPath
+	draftStepValueType
+	draftStepInterval
+	draftStepCriteria
+)
+
+// Notification criteria user input formats.
+var (
+	// Compares the value itself, e.g. "<= 50" or ">3.5"
+	valueCriteriaRegex = regexp.MustCompile(`^\s*(<=|>=|<|>|=)\s*(\S+)\s*$`)
+	// Checks the value is within a range, e.g. "between 3.5 and 5"
+	betweenCriteriaRegex = regexp.MustCompile(`(?i)^\s*between\s+(\S+)\s+and\s+(\S+)\s*$`)
+	// Compares the value change, e.g. "drop >= 5%", "change > 2" or "rise >= 10% baseline"
+	changeCriteriaRegex = regexp.MustCompile(`(?i)^\s*(drop|rise|change)\s*(<=|>=|<|>|=)\s*([^\s%]+)\s*(%)?\s*(baseline)?\s*$`)
+	// Checks the value change is within a range, e.g. "drop between 2 and 5%"
+	changeBetweenCriteriaRegex = regexp.MustCompile(`(?i)^\s*(drop|rise|change)\s+between\s+([^\s%]+)\s*%?\s+and\s+([^\s%]+)\s*(%)?\s*(baseline)?\s*$`)
+	// Any change of the value, e.g. "any change" or "any change baseline"
+	anyChangeCriteriaRegex = regexp.MustCompile(`(?i)^\s*any\s+change\s*(baseline)?\s*$`)
+	// New all-time extreme, e.g. "all-time low"
+	allTimeCriteriaRegex = regexp.MustCompile(`(?i)^\s*all[-\s]?time\s+(low|high)\s*$`)
+	// Extreme within a period, e.g. "lowest 30d"
+	periodCriteriaRegex = regexp.MustCompile(`(?i)^\s*(lowest|highest)\s+(\S+)\s*$`)
+	// Moving average crossing, e.g. "cross below 7d"
+	movingAverageCriteriaRegex = regexp.MustCompile(`(?i)^\s*cross\s+(below|above|any)\s+(\S+)\s*$`)
+	// Compares the text value, e.g. "equals In stock", "contains available" or "regex ^In stock"
+	textCriteriaRegex = regexp.MustCompile(`(?i)^\s*(equals|contains|regex)\s+(.+?)\s*$`)
+	// A group of criteria separated by ';', e.g. "all: >= 3.5; < 5"
+	groupCriteriaRegex = regexp.MustCompile(`(?i)^\s*(all|any)\s*:\s*(.+)$`)
+)
+
+// Explains the notification criteria input formats in the prompts.
+const criteriaInputFormats = "Formats:\n" +
+	"<i>[operator] [value]</i> - compares the value, e.g. <i>&lt;= 50</i>\n" +
+	"<i>between [value] and [value]</i> - the value is within the range, e.g. <i>between 3.5 and 5</i>\n" +
+	"<i>drop|rise|change [operator] [amount][%]</i> - compares the change since the previous run, e.g. <i>drop &gt;= 5%</i>\n" +
+	"<i>any change</i> - any change since the previous run\n" +
+	"<i>all-time low|high</i> - a new all-time low or high\n" +
+	"<i>lowest|highest [period]</i> - the lowest or highest value within the period, e.g. <i>lowest 30d</i>\n" +
+	"<i>cross below|above|any [period]</i> - crossing the moving average of the period, e.g. <i>cross below 7d</i>\n" +
+	"<i>all|any: [criteria]; [criteria]</i> - all or any of the criteria, e.g. <i>all: &gt;= 3.5; &lt; 5</i>\n" +
+	"<i>equals|contains|regex [text]</i> - compares the text of the text value trackers, e.g. <i>contains in stock</i>\n\n" +
+	"Add 'baseline' at the end to compare with the value at the tracker start instead of the previous run; 'between' can be used instead of an operator for the change criteria too.\n\n" +
+	"Available operators: '&lt;', '&lt;=', '=', '&gt;=', '&gt;'"
+
+const valueTypePrompt = "What kind of value does it extract?\n\n" +
+	"<b>number</b> - a price or another number compared with the numeric criteria\n" +
+	"<b>text</b> - text used as is, e.g. an availability label compared with the <i>equals</i>, <i>contains</i>, <i>regex</i> and <i>any change</i> criteria"
+
+// Holds the tracker configuration collected so far during the /add command conversation.
+type trackerDraft struct {
+	step        int
+	trackerType string
+	tracker     *config.Tracker
+}
+
+/*
+Adds a new tracker through a guided conversation - every answer of the user is passed back to this handler
+as the command input (see HandleUserInput) and is processed according to the current draft step.
+Once all the data is collected, the tracker is validated, added to the configuration and persisted.
+*/
+func (ch *CommandHandler) handleAdd(code string, chatID int64, commandParam *string) error {
+	navigationState := ch.GetUserNavigationState(chatID)
+	draft := navigationState.trackerDraft
+
+	if draft == nil {
+		draft = &trackerDraft{tracker: &config.Tracker{}}
+		navigationState.trackerDraft = draft
+
+		// User input is appended to the command on top of the navigation stack so it must not carry any parameters
+		if current := navigationState.Peek(); current != nil {
+			current.Params = nil
+		}
+
+		// The tracker code may also be passed directly with the command
+		if code == "" {
+			ch.promptUserInput(chatID, "Let's add a new tracker!\n\nSend me the tracker code - an arbitrary unique name; it cannot contain '_', '/' or spaces", nil)
+
+			return nil
+		}
+	}
+
+	input := strings.TrimSpace(code + " " + utilities.GetStringPointerValue(commandParam))
+
+	return ch.processTrackerDraftInput(chatID, draft, input)
+}
+
+func (ch *CommandHandler) processTrackerDraftInput(chatID int64, draft *trackerDraft, input string) error {
+	switch draft.step {
+	case draftStepCode:
+		draft.tracker.Code = input
+		if err := config.ValidateTracker(draft.tracker, "Code"); err != nil {
+			ch.promptUserInput(chatID, "Invalid tracker code, it cannot be empty or contain '_', '/' or spaces. Try again!", nil)
+			return err
+		}
+
+		if ch.config.GetTrackerData(input) != nil {
+			ch.promptUserInput(chatID, "A tracker with this code already exists, send me a different one", nil)
+			return errors.New("tracker code already exists")
+		}
+
+		ch.promptUserInput(chatID, "What type of tracker is it?\n\n<b>api</b> - extracts the value from a public API JSON response\n<b>scraper</b> - extracts the value from website HTML", helpers.GetOptionsCustomMenu(API, Scraper))
+
+	case draftStepType:
+		trackerType := strings.ToLower(input)
+		if trackerType != API && trackerType != Scraper {
+			ch.promptUserInput(chatID, "Unsupported tracker type, send me either 'api' or 'scraper'", helpers.GetOptionsCustomMenu(API, Scraper))
+			return errors.New("unsupported tracker type")
+		}
+
+		draft.trackerType = trackerType
+		ch.promptUserInput(chatID, "Send me the URL the data should be fetched from", nil)
+
+	case draftStepDataURL:
+		draft.tracker.DataURL = input
+		if err := config.ValidateTracker(draft.tracker, "DataURL"); err != nil {
+			ch.promptUserInput(chatID, "Invalid URL, try again!", nil)
+			return err
+		}
+
+		ch.promptUserInput(chatID, "Send me the website URL to include in the notification messages or 'skip' if there is none", helpers.GetOptionsCustomMenu(skipInput))
+
+	case draftStepViewURL:
+		if strings.ToLower(input) != skipInput {
+			draft.tracker.ViewURL = input
+			if err := config.ValidateTracker(draft.tracker, "ViewURL"); err != nil {
+				ch.promptUserInput(chatID, "Invalid URL, try again!", helpers.GetOptionsCustomMenu(skipInput))
+				return err
+			}
+		}
+
+		pathFormat := "a <a href=\"https://github.com/tidwall/gjson\">gjson</a> path to the value in the response JSON"
+		if draft.trackerType == Scraper {
+			pathFormat = "a <a href=\"https://pkg.go.dev/github.com/PuerkitoBio/goquery\">goquery</a> selector of the HTML element holding the value"
+		}
+
+		ch.promptUserInput(chatID, "Send me the data extraction path - "+pathFormat, nil)
+
+	case draftStepExtractionPath:
+		draft.tracker.DataExtractionPath = input
+		if err := config.ValidateTracker(draft.tracker, "DataExtractionPath"); err != nil {
+			ch.promptUserInput(chatID, "Invalid data extraction path, try again!", nil)
+			return err
+		}
+
+		ch.promptUserInput(chatID, valueTypePrompt, helpers.GetOptionsCustomMenu(config.ValueTypeNumber, config.ValueTypeText))
+
+	case draftStepValueType:
+		valueType := strings.ToLower(input)
+		if valueType != config.ValueTypeNumber && valueType != config.ValueTypeText {
+			ch.promptUserInput(chatID, "Unsupported value type, send me either 'number' or 'text'", helpers.GetOptionsCustomMenu(config.ValueTypeNumber, config.ValueTypeText))
+			return errors.New("unsupported value type")
+		}
+
+		if valueType == config.ValueTypeText {
+			draft.tracker.ValueType = valueType
+		}
+
+		ch.promptInput(chatID, inputTypeInterval, "Send me the tracker run interval\n\nThe format: <i>[number][interval type*]</i>\n\nAvailable interval types: \n'm'(minute), 'h'(hour), 'd'(day)", helpers.GetIntervalCustomMenu())
+
+	case draftStepInterval:
+		if _, err := utilities.ParseDurationWithDays(input); err != nil {
+			ch.promptInput(chatID, inputTypeInterval, "Invalid interval value. Available interval types: 'm'(minute), 'h'(hour), 'd'(day)", helpers.GetIntervalCustomMenu())
+			return err
+		}
+
+		draft.tracker.Interval = input
+		ch.promptUserInput(chatID, "Send me a notification criteria\n\n"+criteriaInputFormats+"\n\nSend 'done' when finished", helpers.GetOptionsCustomMenu(doneInput))
+
+	case draftStepCriteria:
+		if strings.ToLower(input) == doneInput {
+			return ch.saveTrackerDraft(chatID, draft)
+		}
+
+		criteria, err := parseNotifyCriteria(input)
+		if err == nil {
+			err = config.ValidateCriteriaValueType(draft.tracker.GetValueType(), []config.NotifyCriteria{*criteria})
+		}
+
+		if err != nil {
+			ch.promptUserInput(chatID, "Invalid notification criteria for a tracker of "+draft.tracker.GetValueType()+" values, try again or send 'done' to finish\n\n"+criteriaInputFormats, helpers.GetOptionsCustomMenu(doneInput))
+			return err
+		}
+
+		draft.tracker.NotifyCriteria = append(draft.tracker.NotifyCriteria, *criteria)
+		ch.promptUserInput(chatID, "Criteria added! Send me another one or 'done' to finish", helpers.GetOptionsCustomMenu(doneInput))
+
+		// Stay on the criteria step until the user is done
+		return nil
+	}
+
+	draft.step++
+
+	return nil
+}
+
+func (ch *CommandHandler) saveTrackerDraft(chatID int64, draft *trackerDraft) error {
+	ch.GetUserNavigationState(chatID).trackerDraft = nil
+	code := draft.tracker.Code
+
+	if err := ch.config.AddTracker(draft.trackerType, draft.tracker); err != nil {
+		log.Printf("[CommandHandler] Error adding tracker '%s': %s", code, err.Error())
+		ch.handleCommandMessage(chatID, "Failed to add the tracker: "+html.EscapeString(err.Error()), nil)
+
+		return err
+	}
+
+	if err := ch.storage.SaveTracker(&storage.StoredTracker{Type: draft.trackerType, Tracker: draft.tracker}); err != nil {
+		log.Printf("[CommandHandler] Error persisting tracker '%s': %s", code, err.Error())
+		ch.config.RemoveTracker(code)
+		ch.handleCommandMessage(chatID, "Failed to save the tracker :(", nil)
+
+		return err
+	}
+
+	log.Printf("[CommandHandler] Added tracker: %s", code)
+
+	menu := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Run tracker", "/run "+code),
+	))
+	ch.handleCommandMessage(chatID, "Tracker <b>"+html.EscapeString(code)+"</b> has been added", &menu)
+
+	return nil
+}
+
+// Removes a tracker that has been added via the bot; trackers defined in the configuration files can only be removed there.
+func (ch *CommandHandler) handleRemove(code string, chatID int64, _ *string) error {
+	if code == "" {
+		ch.handleCommandMessage(chatID, "No tracker code provided", nil)
+		return errors.New("no tracker code provided")
+	}
+
+	storedTrackers, err := ch.storage.GetTrackers()
+	if err != nil {
+		log.Printf("[CommandHandler] Error loading stored trackers: %s", err.Error())
+		ch.handleCommandMessage(chatID, "Failed to remove the tracker :(", nil)
+
+		return err
+	}
+
+	if !containsStoredTracker(storedTrackers, code) {
+		message := "Tracker '" + code + "' not found"
+		if ch.config.GetTrackerData(code) != nil {
+			message = "Tracker '" + code + "' is defined in the configuration files and can only be removed there"
+		}
+
+		ch.handleCommandMessage(chatID, message, nil)
+
+		return errors.New("tracker cannot be removed")
+	}
+
+	// The tracker is removed for everyone so it is stopped in all the chats running it
+	for _, tracker := range ch.getRunningTrackers() {
+		if tracker.Code == code {
+			tracker.Stop()
+			ch.RemoveRunningTracker(tracker.chatID, code)
+			ch.notifySubscribersStopped(tracker)
+		}
+	}
+
+	if err := ch.storage.DeleteTracker(code); err != nil {
+		log.Printf("[CommandHandler] Error deleting stored tracker '%s': %s", code, err.Error())
+		ch.handleCommandMessage(chatID, "Failed to remove the tracker :(", nil)
+
+		return err
+	}
+
+	ch.deleteCriteriaOverrides(code)
+
+	ch.config.RemoveTracker(code)
+	log.Printf("[CommandHandler] Removed tracker: %s", code)
+	ch.handleCommandMessage(chatID, "Tracker <b>"+code+"</b> has been removed", nil)
+
+	return nil
+}
+
+// Adds the trackers created via the bot to the configuration.
+func (ch *CommandHandler) loadStoredTrackers() {
+	storedTrackers, err := ch.storage.GetTrackers()
+	if err != nil {
+		log.Printf("[CommandHandler] Error loading stored trackers: %s", err.Error())
+		return
+	}
+
+	for _, stored := range storedTrackers {
+		if err := ch.config.AddTracker(stored.Type, stored.Tracker); err != nil {
+			log.Printf("[CommandHandler] Failed to load stored tracker '%s': %s", stored.Tracker.Code, err.Error())
+		}
+	}
+}
+
+/*
+Views and edits tracker notification criteria. Supported command parameters:
+  - none - lists the current criteria with buttons for deleting each of them or adding a new one
+  - add [operator] [value] - adds a new criteria; the user is prompted for the criteria if it is not provided
+  - delete [index] - deletes the criteria with the given index
+  - reset - restores the configured criteria
+
+Criteria are chat specific - changes are applied to the tracker running in the chat immediately and persisted.
+*/
+func (ch *CommandHandler) handleCriteria(code string, chatID int64, commandParam *string) error {
+	if code == "" {
+		ch.handleCommandMessage(chatID, "No tracker code provided", nil)
+		return errors.New("no tracker code provided")
+	}
+
+	trackerData := ch.config.GetTrackerData(code)
+	if trackerData == nil {
+		ch.handleCommandMessage(chatID, "Tracker '"+code+"' not found", nil)
+		return errors.New("tracker not found")
+	}
+
+	action, actionValue, _ := strings.Cut(utilities.GetStringPointerValue(commandParam), " ")
+
+	override, err := ch.storage.GetCriteriaOverride(chatID, code)
+	if err != nil {
+		log.Printf("[CommandHandler] Error loading notification criteria override of tracker '%s': %s", code, err.Error())
+		ch.handleCommandMessage(chatID, "Failed to load the notification criteria :(", nil)
+
+		return err
+	}
+
+	criteria := append([]config.NotifyCriteria(nil), trackerData.NotifyCriteria...)
+	if override != nil {
+		criteria = append([]config.NotifyCriteria(nil), override.NotifyCriteria...)
+	}
+
+	switch action {
+	case "":
+		ch.sendCriteriaList(chatID, code, criteria, override != nil, "")
+
+		return nil
+
+	case criteriaActionReset:
+		if err := ch.resetNotifyCriteria(chatID, code); err != nil {
+			ch.handleCommandMessage(chatID, "Failed to reset the notification criteria :(", nil)
+			return err
+		}
+
+		if current := ch.GetUserNavigationState(chatID).Peek(); current != nil {
+			current.Params = []string{code}
+		}
+
+		ch.sendCriteriaList(chatID, code, trackerData.NotifyCriteria, false, "Notification criteria reset to the configured ones!\n\n")
+
+		return nil
+
+	case criteriaActionAdd:
+		if actionValue == "" {
+			ch.promptUserInput(chatID, "Send me the new notification criteria\n\n"+criteriaInputFormats, nil)
+			return nil
+		}
+
+		newCriteria, err := parseNotifyCriteria(actionValue)
+		if err != nil {
+			ch.handleCommandMessage(chatID, "Invalid notification criteria\n\n"+criteriaInputFormats, nil)
+			return err
+		}
+
+		if err := config.ValidateCriteriaValueType(trackerData.GetValueType(), []config.NotifyCriteria{*newCriteria}); err != nil {
+			ch.handleCommandMessage(chatID, "This criteria cannot be used by tracker <b>"+html.EscapeString(code)+"</b> as it tracks "+trackerData.GetValueType()+" values", nil)
+			return err
+		}
+
+		criteria = append(criteria, *newCriteria)
+
+	case criteriaActionDelete:
+		index, err := strconv.Atoi(actionValue)
+		if err != nil || index < 0 || index >= len(criteria) {
+			ch.handleCommandMessage(chatID, "Notification criteria not found, it has probably already been deleted", nil)
+			return errors.New("invalid criteria index")
+		}
+
+		criteria = append(criteria[:index], criteria[index+1:]...)
+
+	default:
+		ch.handleCommandMessage(chatID, "Unrecognized criteria action '"+html.EscapeString(action)+"'", nil)
+		return errors.New("unrecognized criteria action")
+	}
+
+	if err := ch.updateNotifyCriteria(chatID, code, criteria); err != nil {
+		ch.handleCommandMessage(chatID, "Failed to update the notification criteria :(", nil)
+		return err
+	}
+
+	// Make the return button lead back to the criteria list instead of repeating the action
+	if current := ch.GetUserNavigationState(chatID).Peek(); current != nil {
+		current.Params = []string{code}
+	}
+
+	ch.sendCriteriaList(chatID, code, criteria, true, "Notification criteria updated!\n\n")
+
+	return nil
+}
+
+func (ch *CommandHandler) updateNotifyCriteria(chatID int64, code string, criteria []config.NotifyCriteria) error {
+	if err := ch.storage.SaveCriteriaOverride(&storage.CriteriaOverride{TrackerCode: code, ChatID: chatID, NotifyCriteria: criteria}); err != nil {
+		log.Printf("[CommandHandler] Error persisting notification criteria for tracker '%s': %s", code, err.Error())
+		return err
+	}
+
+	if tracker := ch.GetActiveTracker(chatID, code); tracker != nil {
+		tracker.SetNotifyCriteria(criteria)
+	}
+
+	log.Printf("[CommandHandler] Updated notification criteria for tracker '%s' in chat %d", code, chatID)
+
+	return nil
+}
+
+func (ch *CommandHandler) resetNotifyCriteria(chatID int64, code string) error {
+	if err := ch.storage.DeleteCriteriaOverride(chatID, code); err != nil {
+		log.Printf("[CommandHandler] Error deleting notification criteria override for tracker '%s': %s", code, err.Error())
+		return err
+	}
+
+	if tracker := ch.GetActiveTracker(chatID, code); tracker != nil {
+		tracker.SetNotifyCriteria(nil)
+	}
+
+	log.Printf("[CommandHandler] Reset notification criteria for tracker '%s' in chat %d", code, chatID)
+
+	return nil
+}
+
+// Deletes the notification criteria overrides of a tracker in all the chats.
+func (ch *CommandHandler) deleteCriteriaOverrides(code string) {
+	overrides, err := ch.storage.GetCriteriaOverrides()
+	if err != nil {
+		log.Printf("[CommandHandler] Error loading notification criteria overrides: %s", err.Error())
+		return
+	}
+
+	for _, override := range overrides {
+		if override.TrackerCode != code {
+			continue
+		}
+
+		if err := ch.storage.DeleteCriteriaOverride(override.ChatID, code); err != nil {
+			log.Printf("[CommandHandler] Error deleting notification criteria override for tracker '%s': %s", code, err.Error())
+		}
+	}
+}
+
+func (ch *CommandHandler) sendCriteriaList(chatID int64, code string, criteria []config.NotifyCriteria, overridden bool, header string) {
+	menu := tgbotapi.NewInlineKeyboardMarkup()
+
+	var builder strings.Builder
+	builder.WriteString(header)
+	builder.WriteString(fmt.Sprintf("<b>Notification criteria for tracker %s</b>\n\n", code))
+
+	if len(criteria) == 0 {
+		builder.WriteString("No notification criteria set\n")
+	}
+
+	for i, c := range criteria {
+		builder.WriteString(fmt.Sprintf(" %d. %s\n", i+1, helpers.FormatNotifyCriteria(c)))
+		menu.InlineKeyboard = append(menu.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Delete: "+helpers.DescribeNotifyCriteria(c), fmt.Sprintf("/criteria %s %s %d", code, criteriaActionDelete, i)),
+		))
+	}
+
+	menu.InlineKeyboard = append(menu.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Add criteria", "/criteria "+code+" "+criteriaActionAdd),
+	))
+
+	if overridden {
+		builder.WriteString("\n<i>The criteria have been changed in this chat</i>\n")
+		menu.InlineKeyboard = append(menu.InlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Reset to the configured criteria", "/criteria "+code+" "+criteriaActionReset),
+		))
+	}
+
+	ch.handleCommandMessage(chatID, builder.String(), &menu)
+}
+
+func parseNotifyCriteria(input string) (*config.NotifyCriteria, error) {
+	var criteria *config.NotifyCriteria
+
+	if matches := groupCriteriaRegex.FindStringSubmatch(input); matches != nil {
+		criteria = &config.NotifyCriteria{Type: config.CriteriaTypeGroup, Match: strings.ToLower(matches[1])}
+
+		for _, part := range strings.Split(matches[2], ";") {
+			if strings.TrimSpace(part) == "" {
+				continue
+			}
+
+			if groupCriteriaRegex.MatchString(part) {
+				return nil, fmt.Errorf("nested criteria groups are not supported: %s", input)
+			}
+
+			child, err := parseNotifyCriteria(part)
+			if err != nil {
+				return nil, err
+			}
+
+			criteria.Criteria = append(criteria.Criteria, *child)
+		}
+	} else if matches := textCriteriaRegex.FindStringSubmatch(input); matches != nil {
+		criteria = &config.NotifyCriteria{Type: strings.ToLower(matches[1]), Value: matches[2]}
+	} else if matches := valueCriteriaRegex.FindStringSubmatch(input); matches != nil {
+		criteria = &config.NotifyCriteria{Operator: matches[1], Value: matches[2]}
+	} else if matches := betweenCriteriaRegex.FindStringSubmatch(input); matches != nil {
+		criteria = &config.NotifyCriteria{Operator: config.OperatorBetween, Value: matches[1], MaxValue: matches[2]}
+	} else if matches := changeBetweenCriteriaRegex.FindStringSubmatch(input); matches != nil {
+		criteria = &config.NotifyCriteria{Type: config.CriteriaTypeChange, Operator: config.OperatorBetween, Value: matches[2], MaxValue: matches[3]}
+		setChangeCriteriaOptions(criteria, matches[1], matches[4] != "", matches[5] != "")
+	} else if matches := changeCriteriaRegex.FindStringSubmatch(input); matches != nil {
+		criteria = &config.NotifyCriteria{Type: config.CriteriaTypeChange, Operator: matches[2], Value: matches[3]}
+		setChangeCriteriaOptions(criteria, matches[1], matches[4] != "", matches[5] != "")
+	} else if matches := anyChangeCriteriaRegex.FindStringSubmatch(input); matches != nil {
+		criteria = &config.NotifyCriteria{Type: config.CriteriaTypeAnyChange}
+		if matches[1] != "" {
+			criteria.Reference = config.ReferenceBaseline
+		}
+	} else if matches := allTimeCriteriaRegex.FindStringSubmatch(input); matches != nil {
+		criteria = &config.NotifyCriteria{Type: config.CriteriaTypeAllTimeLow}
+		if strings.ToLower(matches[1]) == "high" {
+			criteria.Type = config.CriteriaTypeAllTimeHigh
+		}
+	} else if matches := periodCriteriaRegex.FindStringSubmatch(input); matches != nil {
+		criteria = &config.NotifyCriteria{Type: config.CriteriaTypePeriodLow, Period: matches[2]}
+		if strings.ToLower(matches[1]) == "highest" {
+			criteria.Type = config.CriteriaTypePeriodHigh
+		}
+	} else if matches := movingAverageCriteriaRegex.FindStringSubmatch(input); matches != nil {
+		criteria = &config.NotifyCriteria{Type: config.CriteriaTypeMovingAverageCross, Period: matches[2]}
+
+		switch strings.ToLower(matches[1]) {
+		case "below":
+			criteria.Direction = config.DirectionDown
+		case "above":
+			criteria.Direction = config.DirectionUp
+		}
+	} else {
+		return nil, fmt.Errorf("invalid notification criteria: %s", input)
+	}
+
+	if err := config.ValidateNotifyCriteria(criteria); err != nil {
+		return nil, err
+	}
+
+	return criteria, nil
+}
+
+// Applies the direction, percent and reference options parsed from the change criteria input.
+func setChangeCriteriaOptions(criteria *config.NotifyCriteria, verb string, percent bool, baseline bool) {
+	switch strings.ToLower(verb) {
+	case "drop":
+		criteria.Direction = config.DirectionDown
+	case "rise":
+		criteria.Direction = config.DirectionUp
+	}
+
+	if percent {
+		criteria.Type = config.CriteriaTypePercentChange
+	}
+
+	if baseline {
+		criteria.Reference = config.ReferenceBaseline
+	}
+}
+
+func containsStoredTracker(storedTrackers []*storage.StoredTracker, code string) bool {
+	for _, stored := range storedTrackers {
+		if stored.Tracker.Code == code {
+			return true
+		}
+	}
+
+	return false
+}
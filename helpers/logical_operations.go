@@ -1,6 +1,9 @@
 package helpers
 
-import "errors"
+import (
+	"errors"
+	"math"
+)
 
 func CompareNumbers(givenValue float64, targetValue float64, operator string) (bool, error) {
 	switch operator {
@@ -18,3 +21,23 @@ func CompareNumbers(givenValue float64, targetValue float64, operator string) (b
 		return false, errors.New("invalid operator")
 	}
 }
+
+// Compares numbers with the target value moved by the margin in the direction that makes the comparison easier to satisfy,
+// e.g. "10.5 <= 10" with margin 1 is true. Used to keep an already fulfilled criteria from flapping around its target.
+func CompareNumbersWithMargin(givenValue float64, targetValue float64, operator string, margin float64) (bool, error) {
+	switch operator {
+	case "<", "<=":
+		return CompareNumbers(givenValue, targetValue+margin, operator)
+	case ">", ">=":
+		return CompareNumbers(givenValue, targetValue-margin, operator)
+	case "=":
+		return math.Abs(givenValue-targetValue) <= margin, nil
+	default:
+		return false, errors.New("invalid operator")
+	}
+}
+
+// Checks whether the value is within the range, both ends inclusive; the range is widened by the margin on both ends.
+func IsInRange(givenValue float64, minValue float64, maxValue float64, margin float64) bool {
+	return givenValue >= minValue-margin && givenValue <= maxValue+margin
+}
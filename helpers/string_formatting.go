@@ -2,6 +2,7 @@ package helpers
 
 import (
 	"fmt"
+	"html"
 	"strings"
 
 	"pricetrackerbot/config"
@@ -12,8 +13,7 @@ func FormatNotificationCriteriaString(notifyCriteria []config.NotifyCriteria) st
 		var builder strings.Builder
 		builder.WriteString("Active notify criteria:\n")
 		for _, criteria := range notifyCriteria {
-			operatorEscaped := strings.ReplaceAll(strings.ReplaceAll(criteria.Operator, "<", "&lt;"), ">", "&gt;")
-			builder.WriteString(fmt.Sprintf(" - tracked value %s %s\n", operatorEscaped, criteria.Value))
+			builder.WriteString(" - " + FormatNotifyCriteria(criteria) + "\n")
 		}
 
 		return builder.String()
@@ -21,3 +21,85 @@ func FormatNotificationCriteriaString(notifyCriteria []config.NotifyCriteria) st
 
 	return ""
 }
+
+// Formats a single notification criteria for HTML messages.
+func FormatNotifyCriteria(criteria config.NotifyCriteria) string {
+	return html.EscapeString(DescribeNotifyCriteria(criteria))
+}
+
+// Returns a plain text description of a notification criteria, e.g. "tracked value dropped by >= 5% since the previous run".
+func DescribeNotifyCriteria(criteria config.NotifyCriteria) string {
+	since := "since the previous run"
+	if criteria.GetReference() == config.ReferenceBaseline {
+		since = "since the tracker start"
+	}
+
+	switch criteria.GetType() {
+	case config.CriteriaTypeGroup:
+		descriptions := make([]string, 0, len(criteria.Criteria))
+		for _, child := range criteria.Criteria {
+			descriptions = append(descriptions, DescribeNotifyCriteria(child))
+		}
+
+		return fmt.Sprintf("%s of: (%s)", criteria.GetMatch(), strings.Join(descriptions, "; "))
+	case config.CriteriaTypeChange:
+		return fmt.Sprintf("tracked value %s by %s %s", changeVerb(criteria.GetDirection()), describeComparison(criteria, ""), since)
+	case config.CriteriaTypePercentChange:
+		return fmt.Sprintf("tracked value %s by %s %s", changeVerb(criteria.GetDirection()), describeComparison(criteria, "%"), since)
+	case config.CriteriaTypeAnyChange:
+		return "tracked value changed " + since
+	case config.CriteriaTypeAllTimeLow:
+		return "tracked value hit a new all-time low"
+	case config.CriteriaTypeAllTimeHigh:
+		return "tracked value hit a new all-time high"
+	case config.CriteriaTypePeriodLow:
+		return "tracked value is the lowest in the last " + criteria.Period
+	case config.CriteriaTypePeriodHigh:
+		return "tracked value is the highest in the last " + criteria.Period
+	case config.CriteriaTypeEquals:
+		return fmt.Sprintf("tracked text equals '%s'", criteria.Value)
+	case config.CriteriaTypeContains:
+		return fmt.Sprintf("tracked text contains '%s'", criteria.Value)
+	case config.CriteriaTypeRegex:
+		return fmt.Sprintf("tracked text matches '%s'", criteria.Value)
+	case config.CriteriaTypeMovingAverageCross:
+		return fmt.Sprintf("tracked value crossed %s the %s moving average", crossDirection(criteria.GetDirection()), criteria.Period)
+	default:
+		return "tracked value " + describeComparison(criteria, "")
+	}
+}
+
+// Describes the comparison of a criteria, e.g. ">= 5%" or "between 2% and 5%".
+func describeComparison(criteria config.NotifyCriteria, unit string) string {
+	if criteria.Operator == config.OperatorBetween {
+		return fmt.Sprintf("between %s%s and %s%s", criteria.Value, unit, criteria.MaxValue, unit)
+	}
+
+	return fmt.Sprintf("%s %s%s", criteria.Operator, criteria.Value, unit)
+}
+
+func EscapeOperator(operator string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(operator, "<", "&lt;"), ">", "&gt;")
+}
+
+func changeVerb(direction string) string {
+	switch direction {
+	case config.DirectionUp:
+		return "rose"
+	case config.DirectionDown:
+		return "dropped"
+	default:
+		return "changed"
+	}
+}
+
+func crossDirection(direction string) string {
+	switch direction {
+	case config.DirectionUp:
+		return "above"
+	case config.DirectionDown:
+		return "below"
+	default:
+		return "over"
+	}
+}
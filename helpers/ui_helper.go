@@ -44,3 +44,15 @@ func GetStatusInlineKeyboard() *tgbotapi.InlineKeyboardMarkup {
 
 	return &statusMenu
 }
+
+// Creates a one time custom keyboard with a single row of the given options.
+func GetOptionsCustomMenu(options ...string) *tgbotapi.ReplyKeyboardMarkup {
+	buttons := make([]tgbotapi.KeyboardButton, 0, len(options))
+	for _, option := range options {
+		buttons = append(buttons, tgbotapi.NewKeyboardButton(option))
+	}
+
+	customKeyboard := tgbotapi.NewOneTimeReplyKeyboard(tgbotapi.NewKeyboardButtonRow(buttons...))
+
+	return &customKeyboard
+}
@@ -0,0 +1,233 @@
+package scheduling
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"pricetrackerbot/config"
+	"pricetrackerbot/utilities"
+)
+
+const (
+	minutesPerHour = 60
+	daysPerWeek    = 7
+	// How many times a cron run may be moved into an active window before giving up; cron expressions
+	// that never meet the windows would otherwise be searched forever
+	maxWindowLookups = 1000
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+/*
+Plan determines when a tracker runs - either every interval or at the times of its cron expressions -
+limited to the active windows of its schedule. Times are in the local timezone, i.e. the one set by TZ.
+*/
+type Plan struct {
+	interval  time.Duration
+	cronSpecs []string
+	cron      []cron.Schedule
+	windows   []window
+}
+
+type window struct {
+	days     []time.Weekday // Days the window starts on; every day if empty
+	daySpecs []string
+	from     int // Minutes since midnight
+	to       int
+}
+
+// Creates the run plan of a tracker; the schedule is optional and the interval is used if it has no cron expressions.
+func NewPlan(interval time.Duration, schedule *config.TrackerSchedule) (*Plan, error) {
+	// The next run would be planned right away over and over again
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid run interval %s; the interval must be positive", interval)
+	}
+
+	plan := &Plan{interval: interval}
+	if schedule == nil {
+		return plan, nil
+	}
+
+	for _, spec := range schedule.Cron {
+		parsed, err := cron.ParseStandard(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression '%s': %w", spec, err)
+		}
+
+		plan.cron = append(plan.cron, parsed)
+		plan.cronSpecs = append(plan.cronSpecs, spec)
+	}
+
+	for _, activeWindow := range schedule.Windows {
+		w, err := parseWindow(activeWindow)
+		if err != nil {
+			return nil, err
+		}
+
+		plan.windows = append(plan.windows, w)
+	}
+
+	return plan, nil
+}
+
+// Whether the tracker should run right after being started - only interval based trackers within an active window do.
+func (p *Plan) RunsImmediately(now time.Time) bool {
+	return len(p.cron) == 0 && p.inWindow(now)
+}
+
+// Returns the planned run following the given time; zero if the cron expressions never meet the active windows.
+func (p *Plan) Next(after time.Time) time.Time {
+	next := p.nextPlanned(after)
+
+	for range maxWindowLookups {
+		if p.inWindow(next) {
+			return next
+		}
+
+		windowStart := p.nextWindowStart(next)
+		if len(p.cron) == 0 {
+			return windowStart
+		}
+
+		next = p.nextPlanned(windowStart.Add(-time.Second))
+	}
+
+	return time.Time{}
+}
+
+// Describes the plan for the status messages, e.g. "every 1h 0m 0s, active 08:00-22:00 (mon, tue)".
+func (p *Plan) Describe() string {
+	description := "every " + utilities.DurationToString(p.interval)
+	if len(p.cron) > 0 {
+		description = "cron " + strings.Join(p.cronSpecs, "; ")
+	}
+
+	windows := make([]string, 0, len(p.windows))
+	for _, w := range p.windows {
+		windowDescription := formatClock(w.from) + "-" + formatClock(w.to)
+		if len(w.daySpecs) > 0 {
+			windowDescription += " (" + strings.Join(w.daySpecs, ", ") + ")"
+		}
+
+		windows = append(windows, windowDescription)
+	}
+
+	if len(windows) > 0 {
+		description += ", active " + strings.Join(windows, ", ")
+	}
+
+	return description
+}
+
+func (p *Plan) nextPlanned(after time.Time) time.Time {
+	if len(p.cron) == 0 {
+		return after.Add(p.interval)
+	}
+
+	var next time.Time
+	for _, schedule := range p.cron {
+		if candidate := schedule.Next(after); next.IsZero() || candidate.Before(next) {
+			next = candidate
+		}
+	}
+
+	return next
+}
+
+func (p *Plan) inWindow(t time.Time) bool {
+	if len(p.windows) == 0 {
+		return true
+	}
+
+	minutes := t.Hour()*minutesPerHour + t.Minute()
+	for _, w := range p.windows {
+		if w.from < w.to && w.startsOn(t.Weekday()) && minutes >= w.from && minutes < w.to {
+			return true
+		}
+
+		// Windows crossing midnight belong to the day they start on
+		if w.from > w.to {
+			if (w.startsOn(t.Weekday()) && minutes >= w.from) || (w.startsOn(t.AddDate(0, 0, -1).Weekday()) && minutes < w.to) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Returns the start of the first active window after the given time.
+func (p *Plan) nextWindowStart(after time.Time) time.Time {
+	var next time.Time
+
+	for day := range daysPerWeek + 1 {
+		date := after.AddDate(0, 0, day)
+		for _, w := range p.windows {
+			if !w.startsOn(date.Weekday()) {
+				continue
+			}
+
+			start := time.Date(date.Year(), date.Month(), date.Day(), w.from/minutesPerHour, w.from%minutesPerHour, 0, 0, after.Location())
+			if start.After(after) && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+
+		if !next.IsZero() {
+			return next
+		}
+	}
+
+	return next
+}
+
+func (w window) startsOn(day time.Weekday) bool {
+	if len(w.days) == 0 {
+		return true
+	}
+
+	for _, d := range w.days {
+		if d == day {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseWindow(activeWindow config.ActiveWindow) (window, error) {
+	w := window{daySpecs: activeWindow.Days}
+
+	for _, day := range activeWindow.Days {
+		weekday, exists := weekdays[strings.ToLower(day)]
+		if !exists {
+			return w, fmt.Errorf("invalid active window day '%s'", day)
+		}
+
+		w.days = append(w.days, weekday)
+	}
+
+	from, err := time.Parse(config.ClockFormat, activeWindow.From)
+	if err != nil {
+		return w, fmt.Errorf("invalid active window start '%s': %w", activeWindow.From, err)
+	}
+
+	to, err := time.Parse(config.ClockFormat, activeWindow.To)
+	if err != nil {
+		return w, fmt.Errorf("invalid active window end '%s': %w", activeWindow.To, err)
+	}
+
+	w.from = from.Hour()*minutesPerHour + from.Minute()
+	w.to = to.Hour()*minutesPerHour + to.Minute()
+
+	return w, nil
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/minutesPerHour, minutes%minutesPerHour)
+}
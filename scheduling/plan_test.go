@@ -0,0 +1,143 @@
+package scheduling
+
+import (
+	"testing"
+	"time"
+
+	"pricetrackerbot/config"
+)
+
+// Returns a time in October 2026; the 19th is a Monday.
+func at(day int, hour int, minute int) time.Time {
+	return time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestPlanNext(t *testing.T) {
+	daytime := []config.ActiveWindow{{From: "08:00", To: "22:00"}}
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		schedule *config.TrackerSchedule
+		after    time.Time
+		want     time.Time
+	}{
+		{
+			name:     "interval without schedule",
+			interval: time.Hour,
+			after:    at(19, 10, 0),
+			want:     at(19, 11, 0),
+		},
+		{
+			name:     "interval within active window",
+			interval: time.Hour,
+			schedule: &config.TrackerSchedule{Windows: daytime},
+			after:    at(19, 10, 0),
+			want:     at(19, 11, 0),
+		},
+		{
+			name:     "interval past active window waits for the next window",
+			interval: time.Hour,
+			schedule: &config.TrackerSchedule{Windows: daytime},
+			after:    at(19, 21, 30),
+			want:     at(20, 8, 0),
+		},
+		{
+			name:     "window limited to a day of the week",
+			interval: time.Hour,
+			schedule: &config.TrackerSchedule{Windows: []config.ActiveWindow{{Days: []string{"mon"}, From: "08:00", To: "22:00"}}},
+			after:    at(19, 21, 30),
+			want:     at(26, 8, 0),
+		},
+		{
+			name:     "cron",
+			interval: time.Hour,
+			schedule: &config.TrackerSchedule{Cron: []string{"0 9 * * *"}},
+			after:    at(19, 10, 0),
+			want:     at(20, 9, 0),
+		},
+		{
+			name:     "earliest of several cron expressions",
+			interval: time.Hour,
+			schedule: &config.TrackerSchedule{Cron: []string{"0 18 * * *", "30 12 * * *"}},
+			after:    at(19, 10, 0),
+			want:     at(19, 12, 30),
+		},
+		{
+			name:     "cron past active window takes the first cron run in the next window",
+			interval: time.Hour,
+			schedule: &config.TrackerSchedule{Cron: []string{"*/30 * * * *"}, Windows: []config.ActiveWindow{{From: "08:00", To: "09:00"}}},
+			after:    at(19, 9, 10),
+			want:     at(20, 8, 0),
+		},
+		{
+			name:     "cron never meeting active window",
+			interval: time.Hour,
+			schedule: &config.TrackerSchedule{Cron: []string{"0 3 * * *"}, Windows: daytime},
+			after:    at(19, 10, 0),
+			want:     time.Time{},
+		},
+		{
+			name:     "window crossing midnight continues after midnight",
+			interval: time.Hour,
+			schedule: &config.TrackerSchedule{Windows: []config.ActiveWindow{{From: "22:00", To: "02:00"}}},
+			after:    at(19, 23, 30),
+			want:     at(20, 0, 30),
+		},
+		{
+			name:     "window crossing midnight ends at its end",
+			interval: time.Hour,
+			schedule: &config.TrackerSchedule{Windows: []config.ActiveWindow{{From: "22:00", To: "02:00"}}},
+			after:    at(20, 1, 30),
+			want:     at(20, 22, 0),
+		},
+		{
+			name:     "window crossing midnight belongs to the day it starts on",
+			interval: time.Hour,
+			schedule: &config.TrackerSchedule{Windows: []config.ActiveWindow{{Days: []string{"fri"}, From: "22:00", To: "02:00"}}},
+			after:    at(23, 23, 30),
+			want:     at(24, 0, 30),
+		},
+		{
+			name:     "window crossing midnight is not active after midnight of its day",
+			interval: time.Hour,
+			schedule: &config.TrackerSchedule{Windows: []config.ActiveWindow{{Days: []string{"fri"}, From: "22:00", To: "02:00"}}},
+			after:    at(23, 0, 30),
+			want:     at(23, 22, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := NewPlan(tt.interval, tt.schedule)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := plan.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPlanInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		schedule *config.TrackerSchedule
+	}{
+		{name: "zero interval", interval: 0},
+		{name: "negative interval", interval: -time.Minute},
+		{name: "invalid cron", interval: time.Hour, schedule: &config.TrackerSchedule{Cron: []string{"every day"}}},
+		{name: "invalid window day", interval: time.Hour, schedule: &config.TrackerSchedule{Windows: []config.ActiveWindow{{Days: []string{"someday"}, From: "08:00", To: "22:00"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPlan(tt.interval, tt.schedule); err == nil {
+				t.Error("NewPlan() error = nil, want an error")
+			}
+		})
+	}
+}
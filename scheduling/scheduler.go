@@ -0,0 +1,393 @@
+package scheduling
+
+import (
+	"container/heap"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// How long a job waits for its busy host at most before being dispatched again; it is woken up once a job of the host finishes.
+const hostBusyRetryDelay = 10 * time.Second
+
+// A job run by the scheduler according to its plan, e.g. a tracker running in a chat.
+type Job struct {
+	ID        string               // Unique identifier; scheduling a job with an ID already scheduled replaces it
+	Plan      *Plan                // When the job runs
+	Host      func() string        // Host the job sends requests to; the runs of jobs of the same host are spaced and limited
+	Run       func()               // Called on the worker pool
+	OnPlanned func(next time.Time) // Optional; called with the next planned run, zero if there is none
+	Backoff   func() time.Duration // Optional; extra delay added to the next planned run, e.g. after failed runs
+}
+
+// Limits applied to the jobs sending requests to the same host.
+type HostLimits struct {
+	Concurrency int           // Maximum number of jobs running at the same time
+	MinSpacing  time.Duration // Minimum time between the starts of the jobs
+}
+
+/*
+Scheduler runs the jobs of all the trackers. It keeps a priority queue of the next planned runs and dispatches
+the due jobs to a bounded pool of workers. Jobs of the same host are limited in how many of them run at the same
+time and how close to each other they start; jobs waiting for their host are postponed. Every planned run gets
+a random delay (jitter) so that jobs planned for the same time, e.g. all the trackers started via /run, are spread out.
+*/
+type Scheduler struct {
+	workers   int
+	limits    HostLimits
+	maxJitter time.Duration
+
+	mu      sync.Mutex
+	queue   jobQueue
+	entries map[string]*jobEntry // Scheduled jobs by ID, including the running ones that are not queued
+	running map[string]bool      // IDs of the jobs being run; a job never runs twice at the same time
+	hosts   map[string]*hostState
+	wake    chan struct{} // Signals the dispatcher that the queue or the hosts have changed
+	work    chan *jobEntry
+	stop    chan struct{}
+}
+
+type jobEntry struct {
+	job       *Job
+	planned   time.Time // Planned run time without the jitter; the base for planning the next run
+	due       time.Time // Time the job is dispatched at
+	host      string
+	waiting   bool // Whether the job has been postponed because its host is running the maximum number of jobs
+	held      bool // Whether the job is due but not queued as its previous run has not finished yet
+	heapIndex int
+}
+
+type hostState struct {
+	running   int
+	lastStart time.Time
+}
+
+func NewScheduler(workers int, limits HostLimits, maxJitter time.Duration) *Scheduler {
+	return &Scheduler{
+		workers:   max(workers, 1),
+		limits:    limits,
+		maxJitter: maxJitter,
+		entries:   make(map[string]*jobEntry),
+		running:   make(map[string]bool),
+		hosts:     make(map[string]*hostState),
+		wake:      make(chan struct{}, 1),
+		work:      make(chan *jobEntry),
+		stop:      make(chan struct{}),
+	}
+}
+
+// Starts the dispatcher and the worker pool.
+func (s *Scheduler) Start() {
+	for range s.workers {
+		go s.worker()
+	}
+
+	go s.dispatch()
+
+	log.Printf("[Scheduler] Started with %d workers", s.workers)
+}
+
+func (s *Scheduler) Stop() {
+	close(s.stop)
+}
+
+// Adds the job to the schedule; the job is run right away (plus jitter) if requested, otherwise at its next planned run.
+// If the job is running at the moment, the requested run waits until the running one has finished.
+func (s *Scheduler) Schedule(job *Job, runImmediately bool) {
+	now := time.Now()
+	entry := &jobEntry{job: job, heapIndex: -1}
+
+	s.mu.Lock()
+	s.removeLocked(job.ID)
+	s.entries[job.ID] = entry
+
+	if runImmediately {
+		entry.planned = now
+		entry.due = now.Add(s.jitter())
+		heap.Push(&s.queue, entry)
+	} else {
+		s.planNextLocked(entry, now)
+	}
+	s.mu.Unlock()
+
+	s.notifyPlanned(entry)
+	s.signal()
+}
+
+// Removes the job from the schedule; a run already in progress is finished but not planned again.
+func (s *Scheduler) Remove(id string) {
+	s.mu.Lock()
+	s.removeLocked(id)
+	s.mu.Unlock()
+
+	s.signal()
+}
+
+func (s *Scheduler) removeLocked(id string) {
+	entry, exists := s.entries[id]
+	if !exists {
+		return
+	}
+
+	delete(s.entries, id)
+	if entry.heapIndex >= 0 {
+		heap.Remove(&s.queue, entry.heapIndex)
+	}
+}
+
+// Queues the next planned run of the job; the job stays registered but is not queued if it has no more runs.
+func (s *Scheduler) planNextLocked(entry *jobEntry, now time.Time) {
+	base := entry.planned
+	if base.IsZero() {
+		base = now
+	}
+
+	next := entry.job.Plan.Next(base)
+	// Runs that took longer than the interval are not caught up with
+	if !next.IsZero() && next.Before(now) {
+		next = entry.job.Plan.Next(now)
+	}
+
+	if !next.IsZero() && entry.job.Backoff != nil {
+		next = next.Add(entry.job.Backoff())
+	}
+
+	entry.planned = next
+	if next.IsZero() {
+		entry.due = time.Time{}
+		log.Printf("[Scheduler] Job '%s' has no planned runs", entry.job.ID)
+
+		return
+	}
+
+	entry.due = next.Add(s.jitter())
+	heap.Push(&s.queue, entry)
+}
+
+func (s *Scheduler) dispatch() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		entry, wait := s.nextDue()
+		if entry != nil {
+			select {
+			case s.work <- entry:
+				continue
+			case <-s.stop:
+				return
+			}
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-timer.C:
+		case <-s.wake:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Returns the due job that may start now, or how long to wait before checking the queue again.
+// Due jobs whose host is busy are postponed until it is expected to be available.
+func (s *Scheduler) nextDue() (*jobEntry, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	for s.queue.Len() > 0 {
+		entry := s.queue[0]
+		if entry.due.After(now) {
+			return nil, entry.due.Sub(now)
+		}
+
+		// Scheduled again while running, e.g. resumed or rescheduled mid-run; queued again once the run finishes
+		if s.running[entry.job.ID] {
+			heap.Pop(&s.queue)
+			entry.held = true
+
+			continue
+		}
+
+		host := entry.job.Host()
+		if availableAt, busy := s.hostAvailableAt(host, now); availableAt.After(now) {
+			entry.due = availableAt
+			entry.waiting = busy
+			heap.Fix(&s.queue, entry.heapIndex)
+
+			continue
+		}
+
+		heap.Pop(&s.queue)
+		entry.host = host
+		entry.waiting = false
+		s.running[entry.job.ID] = true
+
+		state := s.hosts[host]
+		if state == nil {
+			state = &hostState{}
+			s.hosts[host] = state
+		}
+
+		state.running++
+		state.lastStart = now
+
+		return entry, 0
+	}
+
+	return nil, time.Hour
+}
+
+// Returns when a job of the host may start considering the spacing, and whether the host is running the maximum number of jobs.
+func (s *Scheduler) hostAvailableAt(host string, now time.Time) (time.Time, bool) {
+	state := s.hosts[host]
+	if host == "" || state == nil {
+		return now, false
+	}
+
+	availableAt := state.lastStart.Add(s.limits.MinSpacing)
+	if s.limits.Concurrency > 0 && state.running >= s.limits.Concurrency {
+		return maxTime(availableAt, now.Add(hostBusyRetryDelay)), true
+	}
+
+	return availableAt, false
+}
+
+// Moves the jobs waiting for the host forward once one of its jobs has finished.
+func (s *Scheduler) wakeWaitingLocked(host string, now time.Time) {
+	waiting := make([]*jobEntry, 0)
+	for _, entry := range s.queue {
+		if entry.waiting && entry.job.Host() == host {
+			waiting = append(waiting, entry)
+		}
+	}
+
+	// Fixing the heap reorders the queue so the entries are collected first
+	for _, entry := range waiting {
+		entry.waiting = false
+		entry.due = now
+		heap.Fix(&s.queue, entry.heapIndex)
+	}
+}
+
+func (s *Scheduler) worker() {
+	for {
+		select {
+		case entry := <-s.work:
+			s.runJob(entry)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) runJob(entry *jobEntry) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[Scheduler] Job '%s' panicked: %v", entry.job.ID, r)
+		}
+
+		s.finishJob(entry)
+	}()
+
+	entry.job.Run()
+}
+
+// Releases the host of a finished job and plans its next run unless the job has been removed or replaced meanwhile.
+func (s *Scheduler) finishJob(entry *jobEntry) {
+	s.mu.Lock()
+
+	if state := s.hosts[entry.host]; state != nil {
+		state.running--
+		if state.running <= 0 && time.Since(state.lastStart) >= s.limits.MinSpacing {
+			delete(s.hosts, entry.host)
+		}
+	}
+
+	s.wakeWaitingLocked(entry.host, time.Now())
+	delete(s.running, entry.job.ID)
+
+	current := s.entries[entry.job.ID]
+	scheduled := current == entry
+	if scheduled {
+		s.planNextLocked(entry, time.Now())
+	} else if current != nil && current.held {
+		current.held = false
+		current.due = time.Now()
+		heap.Push(&s.queue, current)
+	}
+	s.mu.Unlock()
+
+	if scheduled {
+		s.notifyPlanned(entry)
+	}
+
+	s.signal()
+}
+
+func (s *Scheduler) notifyPlanned(entry *jobEntry) {
+	if entry.job.OnPlanned != nil {
+		entry.job.OnPlanned(entry.planned)
+	}
+}
+
+func (s *Scheduler) jitter() time.Duration {
+	if s.maxJitter <= 0 {
+		return 0
+	}
+
+	return rand.N(s.maxJitter) //nolint:gosec
+}
+
+func (s *Scheduler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func maxTime(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
+
+// Priority queue of the scheduled jobs ordered by their due time.
+type jobQueue []*jobEntry
+
+func (q jobQueue) Len() int           { return len(q) }
+func (q jobQueue) Less(i, j int) bool { return q[i].due.Before(q[j].due) }
+
+func (q jobQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].heapIndex = i
+	q[j].heapIndex = j
+}
+
+func (q *jobQueue) Push(x any) {
+	entry := x.(*jobEntry) //nolint:forcetypeassert
+	entry.heapIndex = len(*q)
+	*q = append(*q, entry)
+}
+
+func (q *jobQueue) Pop() any {
+	old := *q
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	entry.heapIndex = -1
+	*q = old[:len(old)-1]
+
+	return entry
+}
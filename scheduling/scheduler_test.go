@@ -0,0 +1,88 @@
+package scheduling
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduleWhileRunning(t *testing.T) {
+	tests := []struct {
+		name       string
+		reschedule func(s *Scheduler, job *Job)
+		wantRuns   int32
+	}{
+		{
+			name:       "immediate run is held until the running one finishes",
+			reschedule: func(s *Scheduler, job *Job) { s.Schedule(job, true) },
+			wantRuns:   2,
+		},
+		{
+			name: "repeated immediate runs are merged into one",
+			reschedule: func(s *Scheduler, job *Job) {
+				for range 3 {
+					s.Schedule(job, true)
+				}
+			},
+			wantRuns: 2,
+		},
+		{
+			name:       "planned run does not start while running",
+			reschedule: func(s *Scheduler, job *Job) { s.Schedule(job, false) },
+			wantRuns:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler := NewScheduler(4, HostLimits{}, 0)
+			scheduler.Start()
+			defer scheduler.Stop()
+
+			plan, err := NewPlan(time.Hour, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var active, maxActive, runs atomic.Int32
+			started := make(chan struct{}, 10)
+			job := &Job{
+				ID:   "1/tracker",
+				Plan: plan,
+				Host: func() string { return "example.com" },
+				Run: func() {
+					current := active.Add(1)
+					for {
+						highest := maxActive.Load()
+						if current <= highest || maxActive.CompareAndSwap(highest, current) {
+							break
+						}
+					}
+
+					started <- struct{}{}
+					time.Sleep(100 * time.Millisecond)
+					runs.Add(1)
+					active.Add(-1)
+				},
+			}
+
+			scheduler.Schedule(job, true)
+			select {
+			case <-started:
+			case <-time.After(time.Second):
+				t.Fatal("job has not started")
+			}
+
+			tt.reschedule(scheduler, job)
+			time.Sleep(400 * time.Millisecond)
+
+			if got := runs.Load(); got != tt.wantRuns {
+				t.Errorf("runs = %d, want %d", got, tt.wantRuns)
+			}
+
+			if got := maxActive.Load(); got != 1 {
+				t.Errorf("concurrent runs = %d, want 1", got)
+			}
+		})
+	}
+}
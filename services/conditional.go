@@ -0,0 +1,40 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Returned for the conditional requests answered with 304 Not Modified - the data has not changed since it was last fetched.
+var ErrNotModified = errors.New("not modified since the last request")
+
+// Identify the version of a fetched resource; sent back with the next request, an unchanged resource is answered with 304 Not Modified.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// Reads the validators of a response; zero if the server does not support conditional requests.
+func ValidatorsFromHeader(header http.Header) Validators {
+	return Validators{ETag: header.Get("ETag"), LastModified: header.Get("Last-Modified")}
+}
+
+func (v Validators) IsZero() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+// Adds the conditional request headers to the request headers unless the request sets them itself.
+func (v Validators) AddHeaders(header http.Header) {
+	if v.ETag != "" && header.Get("If-None-Match") == "" {
+		header.Set("If-None-Match", v.ETag)
+	}
+
+	if v.LastModified != "" && header.Get("If-Modified-Since") == "" {
+		header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// Whether the validators may be sent with a request of the method; only the requests reading data are made conditional.
+func CanBeConditional(method string) bool {
+	return method == "" || method == http.MethodGet || method == http.MethodHead
+}
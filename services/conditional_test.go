@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPClientConditionalRequests(t *testing.T) {
+	const (
+		etag         = `"v1"`
+		lastModified = "Mon, 19 Oct 2026 10:00:00 GMT"
+	)
+
+	tests := []struct {
+		name                string
+		method              string
+		validators          Validators
+		wantIfNoneMatch     string
+		wantIfModifiedSince string
+		wantNotModified     bool
+	}{
+		{name: "first request", method: http.MethodGet},
+		{name: "etag sent back", method: http.MethodGet, validators: Validators{ETag: etag}, wantIfNoneMatch: etag, wantNotModified: true},
+		{name: "last modified sent back", method: http.MethodGet, validators: Validators{LastModified: lastModified}, wantIfModifiedSince: lastModified, wantNotModified: true},
+		{
+			name:                "both sent back",
+			method:              http.MethodGet,
+			validators:          Validators{ETag: etag, LastModified: lastModified},
+			wantIfNoneMatch:     etag,
+			wantIfModifiedSince: lastModified,
+			wantNotModified:     true,
+		},
+		{name: "post is never conditional", method: http.MethodPost, validators: Validators{ETag: etag, LastModified: lastModified}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ifNoneMatch, ifModifiedSince string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ifNoneMatch, ifModifiedSince = r.Header.Get("If-None-Match"), r.Header.Get("If-Modified-Since")
+				if ifNoneMatch == etag || ifModifiedSince == lastModified {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+
+				w.Header().Set("ETag", etag)
+				w.Header().Set("Last-Modified", lastModified)
+				_, _ = w.Write([]byte(`{"price":12.99}`))
+			}))
+			defer server.Close()
+
+			response, err := newTestClient(nil).Do(context.Background(), &Request{URL: server.URL, Method: tt.method, Validators: tt.validators})
+
+			if ifNoneMatch != tt.wantIfNoneMatch || ifModifiedSince != tt.wantIfModifiedSince {
+				t.Errorf("If-None-Match = %q, If-Modified-Since = %q, want %q, %q", ifNoneMatch, ifModifiedSince, tt.wantIfNoneMatch, tt.wantIfModifiedSince)
+			}
+
+			if tt.wantNotModified {
+				if !errors.Is(err, ErrNotModified) {
+					t.Errorf("Do() = %v, want %v", err, ErrNotModified)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := (Validators{ETag: etag, LastModified: lastModified}); response.Validators != want {
+				t.Errorf("response validators = %+v, want %+v", response.Validators, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// The request could not be sent or the response could not be read, e.g. the host cannot be resolved or refuses connections.
+type NetworkError struct {
+	URL string
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("network error requesting %s: %s", e.URL, e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// The host did not respond in time.
+type TimeoutError struct {
+	URL string
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("request to %s timed out: %s", e.URL, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// The host responded with a status code other than 200 OK.
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d from %s", e.StatusCode, e.URL)
+}
+
+// Whether repeating the request may succeed - the host is overloaded, rate limiting or failing temporarily.
+func (e *HTTPStatusError) IsTemporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// The request could not be sent through the proxy, e.g. the proxy is down or rejects the credentials.
+type ProxyError struct {
+	Proxy string // Proxy URL without the password
+	Err   error
+}
+
+func (e *ProxyError) Error() string {
+	return fmt.Sprintf("proxy %s failed: %s", e.Proxy, e.Err)
+}
+
+func (e *ProxyError) Unwrap() error {
+	return e.Err
+}
+
+// The response body is larger than the client accepts.
+type ResponseTooLargeError struct {
+	URL   string
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response from %s exceeds the size limit of %d bytes", e.URL, e.Limit)
+}
+
+// The robots.txt of the host disallows the bot to request the URL.
+type RobotsBlockedError struct {
+	URL string
+}
+
+func (e *RobotsBlockedError) Error() string {
+	return fmt.Sprintf("robots.txt disallows requesting %s", e.URL)
+}
+
+// Wraps an error of sending a request into a TimeoutError or a NetworkError; errors classified already, e.g. by the transport, are unwrapped.
+func ClassifyRequestError(url string, err error) error {
+	if classified := findClassified(err); classified != nil {
+		return classified
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &TimeoutError{URL: url, Err: err}
+	}
+
+	return &NetworkError{URL: url, Err: err}
+}
+
+// Returns the classified error the error wraps, e.g. a ProxyError wrapped by the client; nil if there is none.
+func findClassified(err error) error {
+	var (
+		tooLargeErr *ResponseTooLargeError
+		statusErr   *HTTPStatusError
+		timeoutErr  *TimeoutError
+		networkErr  *NetworkError
+		proxyErr    *ProxyError
+	)
+
+	switch {
+	case errors.As(err, &proxyErr):
+		return proxyErr
+	case errors.As(err, &tooLargeErr):
+		return tooLargeErr
+	case errors.As(err, &statusErr):
+		return statusErr
+	case errors.As(err, &timeoutErr):
+		return timeoutErr
+	case errors.As(err, &networkErr):
+		return networkErr
+	default:
+		return nil
+	}
+}
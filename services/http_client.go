@@ -0,0 +1,357 @@
+package services
+
+import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Delay before retrying a response without a Retry-After header; doubled for every next retry.
+const retryBaseDelay = time.Second
+
+// Settings of the HTTP client shared by the trackers.
+type ClientOptions struct {
+	Timeout       time.Duration // Default timeout of a request, including its redirects and retries
+	MaxRedirects  int           // Redirects followed before the redirect response is returned as is
+	MaxBodySize   int64         // Larger responses fail with ResponseTooLargeError; no limit if 0
+	RetryAttempts int           // Retries of the 429 and 5xx responses
+	RetryMaxWait  time.Duration // Longest Retry-After wait honored; responses asking to wait longer are not retried
+	UserAgent     string        // Sent with the requests not setting their own
+	Proxies       *ProxyPool    // Used by the requests without their own proxies; the proxy environment variables if nil
+	Politeness    PolitenessOptions
+}
+
+// The default settings, used until the client is configured.
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		Timeout:       15 * time.Second, //nolint:mnd
+		MaxRedirects:  5,                //nolint:mnd
+		MaxBodySize:   10 << 20,         //nolint:mnd
+		RetryAttempts: 2,                //nolint:mnd
+		RetryMaxWait:  30 * time.Second, //nolint:mnd
+		UserAgent:     "Mozilla/5.0 (compatible; pricetrackerbot)",
+		Politeness: PolitenessOptions{
+			Default:        DomainPolicy{ObeyRobots: true},
+			RobotsCacheTTL: 24 * time.Hour, //nolint:mnd
+		},
+	}
+}
+
+/*
+HTTPClient sends the requests of both the API and the scraper trackers. Requests time out, redirects are followed up to
+a limit and the response bodies are limited in size and decompressed. Responses asking to slow down (429) and server
+errors (5xx) are retried with a growing delay or after the time the Retry-After header asks for.
+*/
+type HTTPClient struct {
+	client  *http.Client
+	options ClientOptions
+
+	proxyPoolsMu sync.Mutex
+	proxyPools   map[string]*ProxyPool // Pools of the trackers by their settings, so that the rotation continues across runs
+
+	politeness *Politeness
+}
+
+var defaultClient atomic.Pointer[HTTPClient]
+
+func NewHTTPClient(options ClientOptions) *HTTPClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.Proxy = proxyFromChoice
+	transport.OnProxyConnectResponse = checkProxyConnectResponse
+
+	client := &HTTPClient{
+		client: &http.Client{
+			Transport: &clientTransport{base: transport, options: options},
+			Timeout:   options.Timeout,
+			CheckRedirect: func(_ *http.Request, via []*http.Request) error {
+				if len(via) > options.MaxRedirects {
+					return http.ErrUseLastResponse
+				}
+
+				return nil
+			},
+		},
+		options:    options,
+		proxyPools: make(map[string]*ProxyPool),
+	}
+	client.politeness = newPoliteness(options.Politeness, client)
+
+	return client
+}
+
+// Replaces the client shared by the trackers; meant to be called once on startup.
+func SetDefaultClient(client *HTTPClient) {
+	defaultClient.Store(client)
+}
+
+// Returns the client shared by the trackers.
+func GetDefaultClient() *HTTPClient {
+	if client := defaultClient.Load(); client != nil {
+		return client
+	}
+
+	defaultClient.CompareAndSwap(nil, NewHTTPClient(DefaultClientOptions()))
+
+	return defaultClient.Load()
+}
+
+func (c *HTTPClient) UserAgent() string {
+	return c.options.UserAgent
+}
+
+// Returns the per-domain limits of the scraping requests, shared by all the trackers using the client.
+func (c *HTTPClient) Politeness() *Politeness {
+	return c.politeness
+}
+
+// Returns the proxy pool of the proxy settings; the same settings share the pool. No proxies send the requests directly.
+func (c *HTTPClient) ProxyPool(proxyURLs []string, rotation string) (*ProxyPool, error) {
+	key := strings.Join(proxyURLs, ",") + "|" + rotation
+
+	c.proxyPoolsMu.Lock()
+	defer c.proxyPoolsMu.Unlock()
+
+	if pool, exists := c.proxyPools[key]; exists {
+		return pool, nil
+	}
+
+	pool, err := NewProxyPool(proxyURLs, rotation)
+	if err != nil {
+		return nil, err
+	}
+	c.proxyPools[key] = pool
+
+	return pool, nil
+}
+
+// Returns a standard library client sharing the connections, retries and limits of the client, e.g. for the scraper collectors.
+// Its requests are aborted once the context is cancelled. A zero timeout keeps the default one; nil proxies use the default ones.
+func (c *HTTPClient) StandardClient(ctx context.Context, timeout time.Duration, proxies *ProxyPool) *http.Client {
+	client := *c.client
+	if timeout > 0 {
+		client.Timeout = timeout
+	}
+
+	if proxies != nil {
+		ctx = withProxyPool(ctx, proxies)
+	}
+	client.Transport = &contextTransport{base: client.Transport, ctx: ctx}
+
+	return &client
+}
+
+// Sends the request and returns the successful response; the method defaults to GET and the Accept header to JSON.
+func (c *HTTPClient) Do(ctx context.Context, request *Request) (*Response, error) {
+	if request.Proxies != nil {
+		ctx = withProxyPool(ctx, request.Proxies)
+	}
+
+	if request.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, request.Timeout)
+		defer cancel()
+	}
+
+	var body io.Reader
+	if request.Body != nil {
+		body = bytes.NewReader(request.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, cmp.Or(request.Method, http.MethodGet), request.URL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	for name, value := range request.Headers {
+		req.Header.Set(name, value)
+	}
+
+	if CanBeConditional(req.Method) {
+		request.Validators.AddHeaders(req.Header)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		log.Println("[HTTP Client] Error doing request", err)
+
+		return nil, ClassifyRequestError(request.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, ErrNotModified
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		log.Println("[HTTP Client] Status code is not OK", resp.StatusCode)
+
+		return nil, &HTTPStatusError{URL: request.URL, StatusCode: resp.StatusCode}
+	}
+
+	reader := io.Reader(resp.Body)
+	// Responses are only decompressed by the transport if the request has not asked for a specific encoding
+	if !resp.Uncompressed && strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, ClassifyRequestError(request.URL, err)
+		}
+		defer gzipReader.Close()
+
+		reader = gzipReader
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, ClassifyRequestError(request.URL, err)
+	}
+
+	return &Response{Body: data, Validators: ValidatorsFromHeader(resp.Header)}, nil
+}
+
+// Round tripper adding the user agent, limiting the response bodies and retrying the transient error responses.
+type clientTransport struct {
+	base    http.RoundTripper
+	options ClientOptions
+}
+
+func (t *clientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" && t.options.UserAgent != "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", t.options.UserAgent)
+	}
+
+	pool, _ := req.Context().Value(proxyPoolKey{}).(*ProxyPool)
+	if pool == nil {
+		pool = t.options.Proxies
+	}
+
+	var proxy *url.URL
+	if pool != nil {
+		proxy = pool.pick(req.URL.Host)
+		req = req.WithContext(context.WithValue(req.Context(), proxyChoiceKey{}, proxyChoice{url: proxy}))
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil && proxy != nil && isProxyFailure(err) {
+			pool.reportFailure(req.URL.Host)
+
+			var proxyErr *ProxyError
+			if !errors.As(err, &proxyErr) {
+				err = &ProxyError{Proxy: proxy.Redacted(), Err: err}
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		delay, retry := t.retryDelay(resp, attempt)
+		if retry && req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			// The body has been consumed and cannot be sent again
+			retry = false
+		}
+
+		if !retry {
+			t.limitBody(resp, req.URL.String())
+			return resp, nil
+		}
+
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10)) //nolint:mnd // Lets the connection be reused
+		resp.Body.Close()
+		log.Printf("[HTTP Client] Status code %d from %s, retrying in %s", resp.StatusCode, req.URL.Host, delay)
+
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+
+		if req.GetBody != nil {
+			// The request belongs to the caller so the new body is set on a copy
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// Returns how long to wait before retrying the response and whether it should be retried at all.
+func (t *clientTransport) retryDelay(resp *http.Response, attempt int) (time.Duration, bool) {
+	if attempt >= t.options.RetryAttempts || !(&HTTPStatusError{StatusCode: resp.StatusCode}).IsTemporary() {
+		return 0, false
+	}
+
+	delay := retryBaseDelay << attempt
+	if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		delay = retryAfter
+	}
+
+	return delay, delay <= t.options.RetryMaxWait
+}
+
+func (t *clientTransport) limitBody(resp *http.Response, url string) {
+	if t.options.MaxBodySize > 0 {
+		resp.Body = &limitedBody{ReadCloser: resp.Body, url: url, limit: t.options.MaxBodySize, remaining: t.options.MaxBodySize}
+	}
+}
+
+// Parses the Retry-After header, either a number of seconds or an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+
+	return 0, false
+}
+
+// Response body failing once more than the limit has been read.
+type limitedBody struct {
+	io.ReadCloser
+	url       string
+	limit     int64
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, &ResponseTooLargeError{URL: b.url, Limit: b.limit}
+	}
+
+	// Reading one byte past the limit tells a body of exactly the limit size from a larger one
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n, &ResponseTooLargeError{URL: b.url, Limit: b.limit}
+	}
+
+	return n, err
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestClient(configure func(options *ClientOptions)) *HTTPClient {
+	options := DefaultClientOptions()
+	options.Politeness = PolitenessOptions{}
+	if configure != nil {
+		configure(&options)
+	}
+
+	return NewHTTPClient(options)
+}
+
+func TestHTTPClientRetries(t *testing.T) {
+	tests := []struct {
+		name          string
+		retryAttempts int
+		statuses      []int  // Statuses of the consecutive responses; the last one repeats
+		retryAfter    string // Retry-After of the error responses
+		wantStatus    int    // Status of the HTTPStatusError returned; 0 if the request succeeds
+		wantRequests  int
+	}{
+		{name: "service unavailable", retryAttempts: 2, statuses: []int{503, 503, 200}, retryAfter: "0", wantRequests: 3},
+		{name: "too many requests", retryAttempts: 2, statuses: []int{429, 200}, retryAfter: "0", wantRequests: 2},
+		{name: "retry after an HTTP date", retryAttempts: 2, statuses: []int{429, 200}, retryAfter: "Mon, 02 Jan 2006 15:04:05 GMT", wantRequests: 2},
+		{name: "retries exhausted", retryAttempts: 1, statuses: []int{500}, retryAfter: "0", wantStatus: 500, wantRequests: 2},
+		{name: "retry after too long", retryAttempts: 2, statuses: []int{503, 200}, retryAfter: "3600", wantStatus: 503, wantRequests: 1},
+		{name: "client errors are not retried", retryAttempts: 2, statuses: []int{404, 200}, retryAfter: "0", wantStatus: 404, wantRequests: 1},
+		{name: "no retries", retryAttempts: 0, statuses: []int{503, 200}, retryAfter: "0", wantStatus: 503, wantRequests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				mu.Lock()
+				status := tt.statuses[min(requests, len(tt.statuses)-1)]
+				requests++
+				mu.Unlock()
+
+				if status != http.StatusOK {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+
+			client := newTestClient(func(options *ClientOptions) { options.RetryAttempts = tt.retryAttempts })
+			_, err := client.Do(context.Background(), &Request{URL: server.URL})
+
+			var statusErr *HTTPStatusError
+			switch {
+			case tt.wantStatus == 0 && err != nil:
+				t.Errorf("Do() = %v, want no error", err)
+			case tt.wantStatus != 0 && (!errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantStatus):
+				t.Errorf("Do() = %v, want status %d", err, tt.wantStatus)
+			}
+
+			if requests != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestHTTPClientRetryReplaysBody(t *testing.T) {
+	const body = `{"query":"bonds"}`
+
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+
+		if len(bodies) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(nil)
+	if _, err := client.Do(context.Background(), &Request{URL: server.URL, Method: http.MethodPost, Body: []byte(body)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bodies) != 2 || bodies[0] != body || bodies[1] != body {
+		t.Errorf("request bodies = %q, want %q sent twice", bodies, body)
+	}
+}
+
+func TestHTTPClientBodyLimit(t *testing.T) {
+	const limit = 16
+
+	tests := []struct {
+		name     string
+		bodySize int
+		wantErr  bool
+	}{
+		{name: "below the limit", bodySize: limit - 1},
+		{name: "exactly the limit", bodySize: limit},
+		{name: "above the limit", bodySize: limit + 1, wantErr: true},
+		{name: "far above the limit", bodySize: 100 * limit, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write(bytes.Repeat([]byte("a"), tt.bodySize))
+			}))
+			defer server.Close()
+
+			client := newTestClient(func(options *ClientOptions) { options.MaxBodySize = limit })
+			response, err := client.Do(context.Background(), &Request{URL: server.URL})
+
+			var tooLargeErr *ResponseTooLargeError
+			if tt.wantErr {
+				if !errors.As(err, &tooLargeErr) {
+					t.Errorf("Do() = %v, want a ResponseTooLargeError", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Do() = %v, want no error", err)
+			}
+
+			if len(response.Body) != tt.bodySize {
+				t.Errorf("body size = %d, want %d", len(response.Body), tt.bodySize)
+			}
+		})
+	}
+}
+
+func TestHTTPClientGzip(t *testing.T) {
+	const body = `{"price":12.99}`
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+	}{
+		{name: "decoded by the transport"},
+		{name: "encoding asked for by the request", headers: map[string]string{"Accept-Encoding": "gzip"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+					_, _ = w.Write([]byte(body))
+					return
+				}
+
+				w.Header().Set("Content-Encoding", "gzip")
+				writer := gzip.NewWriter(w)
+				_, _ = writer.Write([]byte(body))
+				_ = writer.Close()
+			}))
+			defer server.Close()
+
+			client := newTestClient(nil)
+			response, err := client.Do(context.Background(), &Request{URL: server.URL, Headers: tt.headers})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(response.Body) != body {
+				t.Errorf("body = %q, want %q", response.Body, body)
+			}
+		})
+	}
+}
+
+func TestHTTPClientTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(nil)
+	_, err := client.Do(context.Background(), &Request{URL: server.URL, Timeout: 50 * time.Millisecond})
+
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Errorf("Do() = %v, want a TimeoutError", err)
+	}
+}
@@ -1,37 +1,38 @@
 package services
 
 import (
-	"errors"
-	"log"
-
-	"github.com/valyala/fasthttp"
+	"context"
+	"time"
 )
 
-func doRequest(url string, requestMethod string) ([]byte, error) {
-	req := fasthttp.AcquireRequest()
-	resp := fasthttp.AcquireResponse()
-	defer fasthttp.ReleaseRequest(req)
-	defer fasthttp.ReleaseResponse(resp)
-
-	req.SetRequestURI(url)
-	req.Header.SetMethod(requestMethod)
-	req.Header.Set("Accept", "application/json")
+// An outgoing HTTP request; the method defaults to GET and the Accept header to JSON.
+type Request struct {
+	URL     string
+	Method  string
+	Headers map[string]string
+	Body    []byte
+	Timeout time.Duration // The client default if zero
+	Proxies *ProxyPool    // The client default proxies if nil
+	// Validators of the last response; sent as the conditional request headers so that unchanged data fails with ErrNotModified
+	Validators Validators
+}
 
-	if err := fasthttp.Do(req, resp); err != nil {
-		log.Println("[DoRequest] Error doing request", err)
+// Body of a successful response with the validators to send with the next request.
+type Response struct {
+	Body       []byte
+	Validators Validators
+}
 
+func GetRequest(url string) ([]byte, error) {
+	response, err := Do(context.Background(), &Request{URL: url})
+	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode() != fasthttp.StatusOK {
-		log.Println("[DoRequest] Status code is not OK", resp.StatusCode())
-
-		return nil, errors.New("status code is not OK")
-	}
-
-	return resp.Body(), nil
+	return response.Body, nil
 }
 
-func GetRequest(url string) ([]byte, error) {
-	return doRequest(url, "GET")
+// Sends the request with the client shared by the trackers and returns the successful response; cancelling the context aborts the request.
+func Do(ctx context.Context, request *Request) (*Response, error) {
+	return GetDefaultClient().Do(ctx, request)
 }
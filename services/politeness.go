@@ -0,0 +1,223 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+// Name the bot is known by in the robots.txt files.
+const robotsAgent = "pricetrackerbot"
+
+// How the requests to a domain are limited so that the bot does not overload the website or get banned.
+type DomainPolicy struct {
+	MinDelay    time.Duration // Minimum time between the starts of the requests to the domain
+	MaxParallel int           // Maximum number of requests to the domain at the same time; no limit if 0
+	ObeyRobots  bool          // Whether the URLs disallowed by the robots.txt of the domain are not requested
+}
+
+type PolitenessOptions struct {
+	Default        DomainPolicy
+	Domains        map[string]DomainPolicy // By domain; the policy of a domain applies to its subdomains too
+	RobotsCacheTTL time.Duration           // How long the robots.txt of a host is kept before being fetched again
+}
+
+/*
+Politeness limits the scraping requests per domain across all the trackers - the requests wait for the minimum delay
+since the previous request to the domain and for a free slot if the domain already has the maximum number of requests
+in progress. The robots.txt files are fetched once per host and cached.
+*/
+type Politeness struct {
+	options PolitenessOptions
+	client  *HTTPClient
+
+	mu      sync.Mutex
+	domains map[string]*domainState
+	robots  map[string]*robotsEntry // By scheme and host
+}
+
+type domainState struct {
+	slots     chan struct{} // Nil if the parallel requests are not limited
+	mu        sync.Mutex
+	nextStart time.Time
+}
+
+type robotsEntry struct {
+	data      *robotstxt.RobotsData
+	fetchedAt time.Time
+}
+
+func newPoliteness(options PolitenessOptions, client *HTTPClient) *Politeness {
+	return &Politeness{
+		options: options,
+		client:  client,
+		domains: make(map[string]*domainState),
+		robots:  make(map[string]*robotsEntry),
+	}
+}
+
+// Returns the policy of the host and the domain it belongs to, the most specific one if several match; the default
+// policy is keyed by the host itself.
+func (p *Politeness) Policy(host string) (DomainPolicy, string) {
+	host = strings.ToLower(host)
+	policy, matched := p.options.Default, ""
+
+	for domain, domainPolicy := range p.options.Domains {
+		if (host == domain || strings.HasSuffix(host, "."+domain)) && len(domain) > len(matched) {
+			policy, matched = domainPolicy, domain
+		}
+	}
+
+	return policy, cmp.Or(matched, host)
+}
+
+// Waits until a request to the host may start according to the policy of its domain. The returned function must be
+// called once the request has finished.
+func (p *Politeness) Acquire(ctx context.Context, host string) (func(), error) {
+	policy, domain := p.Policy(host)
+	state := p.domainState(domain, policy)
+
+	if state.slots != nil {
+		select {
+		case state.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	release := func() {
+		if state.slots != nil {
+			<-state.slots
+		}
+	}
+
+	// Every request reserves its start time so that the waiting requests are spaced from each other too
+	state.mu.Lock()
+	start := maxTime(state.nextStart, time.Now())
+	state.nextStart = start.Add(policy.MinDelay)
+	state.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+
+	return release, nil
+}
+
+func (p *Politeness) domainState(domain string, policy DomainPolicy) *domainState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, exists := p.domains[domain]
+	if !exists {
+		state = &domainState{}
+		if policy.MaxParallel > 0 {
+			state.slots = make(chan struct{}, policy.MaxParallel)
+		}
+		p.domains[domain] = state
+	}
+
+	return state
+}
+
+// Returns a RobotsBlockedError if the domain policy obeys robots.txt and the robots.txt of the host disallows the URL.
+// The URL is allowed if the robots.txt cannot be fetched, e.g. the host is down.
+func (p *Politeness) CheckRobots(ctx context.Context, rawURL string) error {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	if policy, _ := p.Policy(parsedURL.Hostname()); !policy.ObeyRobots {
+		return nil
+	}
+
+	robots, err := p.getRobots(ctx, parsedURL)
+	if err != nil {
+		log.Printf("[Politeness] Failed to fetch robots.txt of %s, allowing the request: %s", parsedURL.Host, err)
+		return nil
+	}
+
+	path := parsedURL.EscapedPath()
+	if parsedURL.RawQuery != "" {
+		path += "?" + parsedURL.RawQuery
+	}
+
+	if !robots.TestAgent(path, robotsAgent) {
+		return &RobotsBlockedError{URL: rawURL}
+	}
+
+	return nil
+}
+
+func (p *Politeness) getRobots(ctx context.Context, target *url.URL) (*robotstxt.RobotsData, error) {
+	key := target.Scheme + "://" + target.Host
+
+	p.mu.Lock()
+	entry, exists := p.robots[key]
+	p.mu.Unlock()
+
+	if exists && time.Since(entry.fetchedAt) < p.options.RobotsCacheTTL {
+		return entry.data, nil
+	}
+
+	robots, err := p.fetchRobots(ctx, key+"/robots.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.robots[key] = &robotsEntry{data: robots, fetchedAt: time.Now()}
+	p.mu.Unlock()
+
+	return robots, nil
+}
+
+func (p *Politeness) fetchRobots(ctx context.Context, robotsURL string) (*robotstxt.RobotsData, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Server errors are temporary; the robots.txt is fetched again by the next request instead of blocking the host
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, &HTTPStatusError{URL: robotsURL, StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return robotstxt.FromStatusAndBytes(resp.StatusCode, body)
+}
+
+func maxTime(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
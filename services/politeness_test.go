@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestPoliteness(options PolitenessOptions) *Politeness {
+	clientOptions := DefaultClientOptions()
+	clientOptions.Politeness = options
+
+	return NewHTTPClient(clientOptions).Politeness()
+}
+
+func TestPolitenessPolicy(t *testing.T) {
+	shop := DomainPolicy{MinDelay: time.Second}
+	api := DomainPolicy{MinDelay: time.Minute}
+	politeness := newTestPoliteness(PolitenessOptions{
+		Default: DomainPolicy{ObeyRobots: true},
+		Domains: map[string]DomainPolicy{"shop.com": shop, "api.shop.com": api},
+	})
+
+	tests := []struct {
+		host       string
+		wantPolicy DomainPolicy
+		wantDomain string
+	}{
+		{host: "shop.com", wantPolicy: shop, wantDomain: "shop.com"},
+		{host: "www.shop.com", wantPolicy: shop, wantDomain: "shop.com"},
+		{host: "WWW.Shop.com", wantPolicy: shop, wantDomain: "shop.com"},
+		{host: "api.shop.com", wantPolicy: api, wantDomain: "api.shop.com"},
+		{host: "v2.api.shop.com", wantPolicy: api, wantDomain: "api.shop.com"},
+		{host: "myshop.com", wantPolicy: DomainPolicy{ObeyRobots: true}, wantDomain: "myshop.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			policy, domain := politeness.Policy(tt.host)
+			if policy != tt.wantPolicy || domain != tt.wantDomain {
+				t.Errorf("Policy(%s) = %+v, %s, want %+v, %s", tt.host, policy, domain, tt.wantPolicy, tt.wantDomain)
+			}
+		})
+	}
+}
+
+func TestPolitenessMinDelay(t *testing.T) {
+	const minDelay = 50 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		hosts    []string
+		minTotal time.Duration // Minimum time all the requests take to start
+		maxTotal time.Duration
+	}{
+		{name: "same host", hosts: []string{"shop.com", "shop.com", "shop.com"}, minTotal: 2 * minDelay, maxTotal: 4 * minDelay},
+		{name: "subdomains share the domain", hosts: []string{"shop.com", "www.shop.com"}, minTotal: minDelay, maxTotal: 3 * minDelay},
+		{name: "different domains", hosts: []string{"shop.com", "other.com"}, minTotal: 0, maxTotal: minDelay / 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			politeness := newTestPoliteness(PolitenessOptions{Domains: map[string]DomainPolicy{"shop.com": {MinDelay: minDelay}}})
+
+			start := time.Now()
+			for _, host := range tt.hosts {
+				release, err := politeness.Acquire(context.Background(), host)
+				if err != nil {
+					t.Fatal(err)
+				}
+				release()
+			}
+
+			if elapsed := time.Since(start); elapsed < tt.minTotal || elapsed > tt.maxTotal {
+				t.Errorf("requests started within %s, want between %s and %s", elapsed, tt.minTotal, tt.maxTotal)
+			}
+		})
+	}
+}
+
+func TestPolitenessMaxParallel(t *testing.T) {
+	politeness := newTestPoliteness(PolitenessOptions{Default: DomainPolicy{MaxParallel: 2}})
+
+	var releases []func()
+	for range 2 {
+		release, err := politeness.Acquire(context.Background(), "shop.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		releases = append(releases, release)
+	}
+
+	// The third request waits for a free slot
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := politeness.Acquire(ctx, "shop.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire() with all slots taken = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// Other domains have slots of their own
+	if release, err := politeness.Acquire(context.Background(), "other.com"); err != nil {
+		t.Fatalf("Acquire() for another domain = %v", err)
+	} else {
+		release()
+	}
+
+	releases[0]()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	release, err := politeness.Acquire(ctx, "shop.com")
+	if err != nil {
+		t.Fatalf("Acquire() after a slot has been released = %v", err)
+	}
+
+	release()
+	releases[1]()
+}
+
+func TestPolitenessCheckRobots(t *testing.T) {
+	tests := []struct {
+		name         string
+		robotsStatus int
+		robots       string
+		obeyRobots   bool
+		path         string
+		wantBlocked  bool
+	}{
+		{name: "allowed page", robotsStatus: http.StatusOK, robots: "User-agent: *\nDisallow: /private", obeyRobots: true, path: "/products/1"},
+		{name: "disallowed page", robotsStatus: http.StatusOK, robots: "User-agent: *\nDisallow: /private", obeyRobots: true, path: "/private/1", wantBlocked: true},
+		{name: "disallowed for the bot only", robotsStatus: http.StatusOK, robots: "User-agent: pricetrackerbot\nDisallow: /", obeyRobots: true, path: "/products/1", wantBlocked: true},
+		{name: "disallowed query", robotsStatus: http.StatusOK, robots: "User-agent: *\nDisallow: /search?q=", obeyRobots: true, path: "/search?q=shoes", wantBlocked: true},
+		{name: "robots not obeyed", robotsStatus: http.StatusOK, robots: "User-agent: *\nDisallow: /", obeyRobots: false, path: "/products/1"},
+		{name: "missing robots", robotsStatus: http.StatusNotFound, obeyRobots: true, path: "/products/1"},
+		{name: "unavailable robots", robotsStatus: http.StatusServiceUnavailable, obeyRobots: true, path: "/products/1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/robots.txt" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				w.WriteHeader(tt.robotsStatus)
+				_, _ = w.Write([]byte(tt.robots))
+			}))
+			defer server.Close()
+
+			clientOptions := DefaultClientOptions()
+			clientOptions.RetryAttempts = 0
+			clientOptions.Politeness = PolitenessOptions{Default: DomainPolicy{ObeyRobots: tt.obeyRobots}, RobotsCacheTTL: time.Hour}
+			politeness := NewHTTPClient(clientOptions).Politeness()
+
+			err := politeness.CheckRobots(context.Background(), server.URL+tt.path)
+
+			var robotsErr *RobotsBlockedError
+			if blocked := errors.As(err, &robotsErr); blocked != tt.wantBlocked || (!blocked && err != nil) {
+				t.Errorf("CheckRobots() = %v, want blocked %t", err, tt.wantBlocked)
+			}
+		})
+	}
+}
+
+func TestPolitenessRobotsCache(t *testing.T) {
+	tests := []struct {
+		name         string
+		robotsStatus int
+		wantFetches  int32
+	}{
+		{name: "fetched robots are cached", robotsStatus: http.StatusOK, wantFetches: 1},
+		{name: "unavailable robots are fetched again", robotsStatus: http.StatusInternalServerError, wantFetches: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetches atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				fetches.Add(1)
+				w.WriteHeader(tt.robotsStatus)
+			}))
+			defer server.Close()
+
+			clientOptions := DefaultClientOptions()
+			clientOptions.RetryAttempts = 0
+			clientOptions.Politeness = PolitenessOptions{Default: DomainPolicy{ObeyRobots: true}, RobotsCacheTTL: time.Hour}
+			politeness := NewHTTPClient(clientOptions).Politeness()
+
+			for range 3 {
+				if err := politeness.CheckRobots(context.Background(), server.URL+"/products/1"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := fetches.Load(); got != tt.wantFetches {
+				t.Errorf("robots.txt fetched %d times, want %d", got, tt.wantFetches)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import "testing"
+
+func TestProxyPoolPick(t *testing.T) {
+	type pick struct {
+		host          string
+		failed        bool // Whether the picked proxy fails the request
+		wantProxyHost string
+	}
+
+	proxies := []string{"http://10.0.0.1:8080", "http://10.0.0.2:8080", "socks5://10.0.0.3:1080"}
+
+	tests := []struct {
+		name     string
+		proxies  []string
+		rotation string
+		picks    []pick
+	}{
+		{
+			name:     "no proxies",
+			rotation: RotationRoundRobin,
+			picks:    []pick{{host: "a.com"}, {host: "b.com"}},
+		},
+		{
+			name:     "round-robin is the default",
+			proxies:  proxies,
+			rotation: "",
+			picks: []pick{
+				{host: "a.com", wantProxyHost: "10.0.0.1:8080"},
+				{host: "a.com", wantProxyHost: "10.0.0.2:8080"},
+			},
+		},
+		{
+			name:     "round-robin goes through the pool regardless of the host",
+			proxies:  proxies,
+			rotation: RotationRoundRobin,
+			picks: []pick{
+				{host: "a.com", wantProxyHost: "10.0.0.1:8080"},
+				{host: "b.com", wantProxyHost: "10.0.0.2:8080"},
+				{host: "a.com", wantProxyHost: "10.0.0.3:1080"},
+				{host: "a.com", wantProxyHost: "10.0.0.1:8080"},
+			},
+		},
+		{
+			name:     "sticky keeps the proxy of a host",
+			proxies:  proxies,
+			rotation: RotationSticky,
+			picks: []pick{
+				{host: "a.com", wantProxyHost: "10.0.0.1:8080"},
+				{host: "b.com", wantProxyHost: "10.0.0.2:8080"},
+				{host: "a.com", wantProxyHost: "10.0.0.1:8080"},
+				{host: "b.com", wantProxyHost: "10.0.0.2:8080"},
+			},
+		},
+		{
+			name:     "sticky moves on after a failure",
+			proxies:  proxies,
+			rotation: RotationSticky,
+			picks: []pick{
+				{host: "a.com", wantProxyHost: "10.0.0.1:8080", failed: true},
+				{host: "a.com", wantProxyHost: "10.0.0.2:8080"},
+				{host: "a.com", wantProxyHost: "10.0.0.2:8080"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, err := NewProxyPool(tt.proxies, tt.rotation)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, p := range tt.picks {
+				proxy := pool.pick(p.host)
+
+				got := ""
+				if proxy != nil {
+					got = proxy.Host
+				}
+
+				if got != p.wantProxyHost {
+					t.Errorf("pick %d for %s = %q, want %q", i, p.host, got, p.wantProxyHost)
+				}
+
+				if p.failed {
+					pool.reportFailure(p.host)
+				}
+			}
+		})
+	}
+}
+
+func TestNewProxyPoolInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		proxies  []string
+		rotation string
+	}{
+		{name: "unsupported rotation", rotation: "random"},
+		{name: "unsupported scheme", proxies: []string{"ftp://10.0.0.1:21"}},
+		{name: "missing host", proxies: []string{"http://"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewProxyPool(tt.proxies, tt.rotation); err == nil {
+				t.Error("NewProxyPool() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package storage
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	priceHistoryBucket = "price_history"
+	trackerStateBucket = "tracker_state"
+	trackersBucket     = "trackers"
+	criteriaBucket     = "criteria_overrides"
+	dbFileMode         = 0o600
+	dbDirMode          = 0o755
+	dbOpenTimeout      = 5 * time.Second
+)
+
+// Storage implementation backed by a single bbolt database file.
+//
+// Price history is kept in a nested bucket per tracker code where keys are big-endian Unix nanosecond
+// timestamps, so records are naturally ordered by time and can be range-scanned with a cursor.
+type BoltStorage struct {
+	db *bolt.DB
+}
+
+func NewBoltStorage(filePath string) (*BoltStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), dbDirMode); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	db, err := bolt.Open(filePath, dbFileMode, &bolt.Options{Timeout: dbOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open storage file: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range []string{priceHistoryBucket, trackerStateBucket, trackersBucket, criteriaBucket} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize storage buckets: %w", err)
+	}
+
+	log.Printf("[Storage] Opened storage file: %s", filePath)
+
+	return &BoltStorage{db: db}, nil
+}
+
+func (s *BoltStorage) SavePriceRecord(record *PriceRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		trackerBucket, err := tx.Bucket([]byte(priceHistoryBucket)).CreateBucketIfNotExists([]byte(record.TrackerCode))
+		if err != nil {
+			return err
+		}
+
+		return trackerBucket.Put(timestampKey(record.Timestamp), data)
+	})
+}
+
+func (s *BoltStorage) GetPriceHistory(trackerCode string, since time.Time) ([]*PriceRecord, error) {
+	records := make([]*PriceRecord, 0)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		trackerBucket := tx.Bucket([]byte(priceHistoryBucket)).Bucket([]byte(trackerCode))
+		if trackerBucket == nil {
+			return nil
+		}
+
+		cursor := trackerBucket.Cursor()
+		var k, v []byte
+		if since.IsZero() {
+			k, v = cursor.First()
+		} else {
+			k, v = cursor.Seek(timestampKey(since))
+		}
+
+		for ; k != nil; k, v = cursor.Next() {
+			var record PriceRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+
+			records = append(records, &record)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (s *BoltStorage) SaveTrackerState(state *TrackerState) error {
+	return s.put(trackerStateBucket, chatKey(state.ChatID, state.TrackerCode), state)
+}
+
+func (s *BoltStorage) DeleteTrackerState(chatID int64, trackerCode string) error {
+	return s.delete(trackerStateBucket, chatKey(chatID, trackerCode))
+}
+
+func (s *BoltStorage) GetTrackerStates() ([]*TrackerState, error) {
+	return getAll[TrackerState](s, trackerStateBucket)
+}
+
+func (s *BoltStorage) SaveTracker(tracker *StoredTracker) error {
+	return s.put(trackersBucket, tracker.Tracker.Code, tracker)
+}
+
+func (s *BoltStorage) DeleteTracker(trackerCode string) error {
+	return s.delete(trackersBucket, trackerCode)
+}
+
+func (s *BoltStorage) GetTrackers() ([]*StoredTracker, error) {
+	return getAll[StoredTracker](s, trackersBucket)
+}
+
+func (s *BoltStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStorage) SaveCriteriaOverride(override *CriteriaOverride) error {
+	return s.put(criteriaBucket, chatKey(override.ChatID, override.TrackerCode), override)
+}
+
+func (s *BoltStorage) DeleteCriteriaOverride(chatID int64, trackerCode string) error {
+	return s.delete(criteriaBucket, chatKey(chatID, trackerCode))
+}
+
+func (s *BoltStorage) GetCriteriaOverride(chatID int64, trackerCode string) (*CriteriaOverride, error) {
+	return get[CriteriaOverride](s, criteriaBucket, chatKey(chatID, trackerCode))
+}
+
+func (s *BoltStorage) GetCriteriaOverrides() ([]*CriteriaOverride, error) {
+	return getAll[CriteriaOverride](s, criteriaBucket)
+}
+
+// Stores a JSON serialized value under the given key in a top level bucket.
+func (s *BoltStorage) put(bucket string, key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucket)).Put([]byte(key), data)
+	})
+}
+
+func (s *BoltStorage) delete(bucket string, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucket)).Delete([]byte(key))
+	})
+}
+
+// Deserializes the value stored under the given key in a top level bucket; nil if there is no such key.
+func get[T any](s *BoltStorage, bucket string, key string) (*T, error) {
+	var value *T
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket([]byte(bucket)).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+
+		value = new(T)
+
+		return json.Unmarshal(data, value)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// Deserializes all the values stored in a top level bucket.
+func getAll[T any](s *BoltStorage, bucket string) ([]*T, error) {
+	values := make([]*T, 0)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucket)).ForEach(func(_, v []byte) error {
+			var value T
+			if err := json.Unmarshal(v, &value); err != nil {
+				return err
+			}
+
+			values = append(values, &value)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// Key of the values stored per chat and tracker; tracker codes cannot contain '/' so the key is unambiguous.
+func chatKey(chatID int64, trackerCode string) string {
+	return fmt.Sprintf("%d/%s", chatID, trackerCode)
+}
+
+func timestampKey(t time.Time) []byte {
+	key := make([]byte, 8) //nolint:mnd
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+
+	return key
+}
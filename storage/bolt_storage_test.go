@@ -0,0 +1,240 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"pricetrackerbot/config"
+)
+
+func newTestStorage(t *testing.T) *BoltStorage {
+	t.Helper()
+
+	store, err := NewBoltStorage(filepath.Join(t.TempDir(), "data", "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+func TestPriceHistory(t *testing.T) {
+	base := time.Date(2026, time.October, 19, 10, 0, 0, 0, time.UTC)
+
+	store := newTestStorage(t)
+	// Saved out of order; the history is ordered by the timestamps
+	for _, record := range []*PriceRecord{
+		{TrackerCode: "bonds", Value: 3, Timestamp: base.Add(2 * time.Hour)},
+		{TrackerCode: "bonds", Value: 1, Timestamp: base},
+		{TrackerCode: "bonds", Value: 2, Timestamp: base.Add(time.Hour)},
+		{TrackerCode: "bonds", Value: 1.5, Timestamp: base.Add(time.Hour - time.Nanosecond)},
+		{TrackerCode: "gold", Value: 100, Timestamp: base.Add(time.Hour)},
+		{TrackerCode: "stock", Text: "In stock", Timestamp: base},
+	} {
+		if err := store.SavePriceRecord(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		code       string
+		since      time.Time
+		wantValues []float64
+	}{
+		{name: "full history", code: "bonds", wantValues: []float64{1, 1.5, 2, 3}},
+		{name: "since a record", code: "bonds", since: base.Add(time.Hour), wantValues: []float64{2, 3}},
+		{name: "since between records", code: "bonds", since: base.Add(30 * time.Minute), wantValues: []float64{1.5, 2, 3}},
+		{name: "since after the last record", code: "bonds", since: base.Add(3 * time.Hour), wantValues: []float64{}},
+		{name: "other tracker", code: "gold", wantValues: []float64{100}},
+		{name: "unknown tracker", code: "silver", wantValues: []float64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := store.GetPriceHistory(tt.code, tt.since)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			values := make([]float64, 0, len(records))
+			for _, record := range records {
+				if record.TrackerCode != tt.code {
+					t.Errorf("record of tracker %s in the history of %s", record.TrackerCode, tt.code)
+				}
+				values = append(values, record.Value)
+			}
+
+			if !reflect.DeepEqual(values, tt.wantValues) {
+				t.Errorf("GetPriceHistory(%s, %s) values = %v, want %v", tt.code, tt.since, values, tt.wantValues)
+			}
+		})
+	}
+
+	records, err := store.GetPriceHistory("stock", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 1 || records[0].Text != "In stock" || !records[0].Timestamp.Equal(base) {
+		t.Errorf("text history = %+v, want a single 'In stock' record at %s", records, base)
+	}
+}
+
+func TestChatKey(t *testing.T) {
+	tests := []struct {
+		chatID int64
+		code   string
+		want   string
+	}{
+		{chatID: 12345, code: "bonds", want: "12345/bonds"},
+		{chatID: -100123, code: "bonds", want: "-100123/bonds"},
+		{chatID: 12345, code: "gold_price", want: "12345/gold_price"},
+	}
+
+	for _, tt := range tests {
+		if got := chatKey(tt.chatID, tt.code); got != tt.want {
+			t.Errorf("chatKey(%d, %s) = %s, want %s", tt.chatID, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestTrackerStates(t *testing.T) {
+	previous := 12.5
+	lastNotified := time.Date(2026, time.October, 19, 10, 0, 0, 0, time.UTC)
+	state := &TrackerState{
+		TrackerCode:       "bonds",
+		ChatID:            1,
+		Interval:          time.Hour,
+		StartTimestamp:    lastNotified.Add(-time.Hour),
+		LastRunTimestamp:  lastNotified,
+		TotalRuns:         3,
+		LastRecordedValue: "12.50",
+		NotificationState: &NotificationState{
+			Criteria:      map[string]*CriteriaState{"value|<|13": {Fulfilled: true, LastNotified: lastNotified}},
+			PreviousValue: &previous,
+		},
+		Subscribers:         []int64{2, 3},
+		Paused:              true,
+		ConsecutiveFailures: 5,
+		ErrorStreakNotified: true,
+	}
+	// The same tracker in another chat has a state of its own
+	otherChatState := &TrackerState{TrackerCode: "bonds", ChatID: 2}
+
+	store := newTestStorage(t)
+	for _, trackerState := range []*TrackerState{state, otherChatState} {
+		if err := store.SaveTrackerState(trackerState); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	states, err := store.GetTrackerStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(states) != 2 {
+		t.Fatalf("GetTrackerStates() returned %d states, want 2", len(states))
+	}
+
+	byChat := map[int64]*TrackerState{states[0].ChatID: states[0], states[1].ChatID: states[1]}
+	if !reflect.DeepEqual(byChat[1], state) {
+		t.Errorf("restored state = %+v, want %+v", byChat[1], state)
+	}
+
+	if err := store.DeleteTrackerState(1, "bonds"); err != nil {
+		t.Fatal(err)
+	}
+
+	states, err = store.GetTrackerStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(states) != 1 || states[0].ChatID != 2 {
+		t.Errorf("states after the delete = %+v, want the state of chat 2 only", states)
+	}
+}
+
+func TestCriteriaOverrides(t *testing.T) {
+	override := &CriteriaOverride{TrackerCode: "bonds", ChatID: 1, NotifyCriteria: []config.NotifyCriteria{{Operator: "<", Value: "13"}}}
+
+	store := newTestStorage(t)
+	if err := store.SaveCriteriaOverride(override); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		chatID int64
+		code   string
+		want   *CriteriaOverride
+	}{
+		{name: "saved override", chatID: 1, code: "bonds", want: override},
+		{name: "other chat", chatID: 2, code: "bonds"},
+		{name: "other tracker", chatID: 1, code: "gold"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.GetCriteriaOverride(tt.chatID, tt.code)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetCriteriaOverride(%d, %s) = %+v, want %+v", tt.chatID, tt.code, got, tt.want)
+			}
+		})
+	}
+
+	if err := store.DeleteCriteriaOverride(1, "bonds"); err != nil {
+		t.Fatal(err)
+	}
+
+	overrides, err := store.GetCriteriaOverrides()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(overrides) != 0 {
+		t.Errorf("overrides after the delete = %+v, want none", overrides)
+	}
+}
+
+func TestDataSurvivesReopening(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test.db")
+
+	store, err := NewBoltStorage(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracker := &StoredTracker{Type: config.TrackerTypeAPI, Tracker: &config.Tracker{Code: "bonds", DataURL: "https://example.com/bonds"}}
+	if err := store.SaveTracker(tracker); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = NewBoltStorage(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	trackers, err := store.GetTrackers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(trackers) != 1 || trackers[0].Type != tracker.Type || trackers[0].Tracker.Code != "bonds" || trackers[0].Tracker.DataURL != tracker.Tracker.DataURL {
+		t.Errorf("trackers after reopening = %+v, want %+v", trackers, tracker)
+	}
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"time"
+
+	"pricetrackerbot/config"
+)
+
+// PriceRecord represents a single value successfully extracted by a tracker run.
+type PriceRecord struct {
+	TrackerCode string    `json:"trackerCode"`
+	Value       float64   `json:"value"`
+	Text        string    `json:"text,omitempty"` // Raw extracted text; the only value recorded by the text value trackers
+	Timestamp   time.Time `json:"timestamp"`
+	SourceURL   string    `json:"sourceUrl"`
+}
+
+// TrackerState represents a running tracker that should be restored after an application restart.
+type TrackerState struct {
+	TrackerCode         string             `json:"trackerCode"`
+	ChatID              int64              `json:"chatId"`
+	Interval            time.Duration      `json:"interval"` // Only set when the interval has been changed from the configured default
+	StartTimestamp      time.Time          `json:"startTimestamp"`
+	LastRunTimestamp    time.Time          `json:"lastRunTimestamp"`
+	TotalRuns           int                `json:"totalRuns"`
+	LastRecordedValue   string             `json:"lastRecordedValue"`
+	NotificationState   *NotificationState `json:"notificationState"`
+	Subscribers         []int64            `json:"subscribers"`                   // Other chats receiving the tracker notifications
+	Paused              bool               `json:"paused,omitempty"`              // Whether the tracker has been paused after repeated failures
+	ConsecutiveFailures int                `json:"consecutiveFailures,omitempty"` // Failed runs since the last successful one
+	ErrorStreakNotified bool               `json:"errorStreakNotified,omitempty"` // Whether the chat has been alerted about the failed runs
+}
+
+// NotificationState holds what the notification criteria evaluation of a tracker remembers between the runs.
+type NotificationState struct {
+	Criteria      map[string]*CriteriaState `json:"criteria"` // By the criteria key
+	PreviousValue *float64                  `json:"previousValue"`
+	BaselineValue *float64                  `json:"baselineValue"`
+	PreviousText  *string                   `json:"previousText"`
+	BaselineText  *string                   `json:"baselineText"`
+	AllTimeLow    *float64                  `json:"allTimeLow"`
+	AllTimeHigh   *float64                  `json:"allTimeHigh"`
+}
+
+// CriteriaState holds the outcome of the last evaluation of a single notification criteria.
+type CriteriaState struct {
+	Fulfilled    bool      `json:"fulfilled"`
+	LastNotified time.Time `json:"lastNotified"`
+}
+
+// StoredTracker represents a tracker configuration added at runtime via the bot.
+type StoredTracker struct {
+	Type    string          `json:"type"`
+	Tracker *config.Tracker `json:"tracker"`
+}
+
+// CriteriaOverride holds the notification criteria of a tracker changed via the bot in a chat; these take precedence over the configured ones.
+type CriteriaOverride struct {
+	TrackerCode    string                  `json:"trackerCode"`
+	ChatID         int64                   `json:"chatId"`
+	NotifyCriteria []config.NotifyCriteria `json:"notifyCriteria"`
+}
+
+/*
+Storage interface is the persistence layer the handlers depend on. Concrete implementations
+are free to use whatever embedded store they like as long as the data survives application restarts.
+*/
+type Storage interface {
+	// Persists a single tracker run result.
+	SavePriceRecord(record *PriceRecord) error
+	// Returns all the recorded values for a tracker starting from the given time, ordered from the oldest to the newest.
+	// A zero time returns the full history.
+	GetPriceHistory(trackerCode string, since time.Time) ([]*PriceRecord, error)
+	// Creates or overwrites the persisted state of a tracker running in a chat.
+	SaveTrackerState(state *TrackerState) error
+	DeleteTrackerState(chatID int64, trackerCode string) error
+	GetTrackerStates() ([]*TrackerState, error)
+	// Creates or overwrites a tracker configuration added via the bot.
+	SaveTracker(tracker *StoredTracker) error
+	DeleteTracker(trackerCode string) error
+	GetTrackers() ([]*StoredTracker, error)
+	// Creates or overwrites the notification criteria override of a tracker in a chat.
+	SaveCriteriaOverride(override *CriteriaOverride) error
+	DeleteCriteriaOverride(chatID int64, trackerCode string) error
+	// Returns nil if there is no override of the tracker in the chat.
+	GetCriteriaOverride(chatID int64, trackerCode string) (*CriteriaOverride, error)
+	GetCriteriaOverrides() ([]*CriteriaOverride, error)
+	Close() error
+}
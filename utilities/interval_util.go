@@ -17,6 +17,8 @@ const (
 // Available time units are "m", "h", "d". Anything other than these is not allowed.
 //
 // Will default to 24 hours when failing to parse a duration ending with "d".
+//
+// Zero and negative durations are not allowed as they make no sense as intervals or periods.
 func ParseDurationWithDays(s string) (time.Duration, error) {
 	if s == "" {
 		return 0, errors.New("empty_interval_value")
@@ -27,17 +29,25 @@ func ParseDurationWithDays(s string) (time.Duration, error) {
 		return 0, errors.New("invalid_interval_value")
 	}
 
+	var duration time.Duration
+	var err error
 	if len(s) > 0 && s[len(s)-1] == 'd' {
 		// If the duration ends with 'd', parse it as days
-		days, err := time.ParseDuration(s[:len(s)-1] + "h")
-		if err != nil {
-			return 0, err
-		}
+		duration, err = time.ParseDuration(s[:len(s)-1] + "h")
+		duration *= day
+	} else {
+		duration, err = time.ParseDuration(s)
+	}
+
+	if err != nil {
+		return 0, err
+	}
 
-		return days * day, nil
+	if duration <= 0 {
+		return 0, errors.New("non_positive_interval_value")
 	}
 
-	return time.ParseDuration(s)
+	return duration, nil
 }
 
 func DurationToString(d time.Duration) string {